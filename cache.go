@@ -0,0 +1,123 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+)
+
+// WithResultCache 为指定的幂等只读方法启用客户端结果缓存：成功结果以（方法名, 编码后的参数）为 key
+// 缓存 ttl 时长，期间相同的调用直接返回缓存结果而不发出请求。错误响应不会被缓存，并会使对应的缓存项失效。
+// 缓存以拦截器的形式工作，作用于 Call、CallWithMeta 与 CallContext，位于手动添加的拦截器之前（最外层）。
+func WithResultCache(ttl time.Duration, methods ...string) DialOption {
+	return dialOptionFunc(func(c *Client) {
+		cache := newResultCache(ttl, methods)
+		c.interceptors = append([]Interceptor{cache.intercept}, c.interceptors...)
+	})
+}
+
+// resultCache 缓存方法调用的原始结果
+type resultCache struct {
+	ttl     time.Duration
+	methods map[string]bool
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+	sweepAt int // 缓存项达到该数量时清理过期项，参见 set
+}
+
+type cacheEntry struct {
+	result  json.RawMessage
+	expires time.Time
+}
+
+func newResultCache(ttl time.Duration, methods []string) *resultCache {
+	rc := &resultCache{
+		ttl:     ttl,
+		methods: make(map[string]bool, len(methods)),
+		entries: make(map[string]cacheEntry),
+		sweepAt: cacheSweepThreshold,
+	}
+	for _, m := range methods {
+		rc.methods[m] = true
+	}
+	return rc
+}
+
+func (rc *resultCache) intercept(next Invoker) Invoker {
+	return func(ctx context.Context, method string, args, reply interface{}) error {
+		if !rc.methods[method] {
+			return next(ctx, method, args, reply)
+		}
+		key, ok := callKey(method, args)
+		if !ok {
+			// 交给后续的调用返回编码错误
+			return next(ctx, method, args, reply)
+		}
+
+		if result, ok := rc.get(key); ok {
+			return decodeResult(result, reply)
+		}
+
+		var result json.RawMessage
+		if err := next(ctx, method, args, &result); err != nil {
+			rc.delete(key)
+			return err
+		}
+		rc.set(key, result)
+		return decodeResult(result, reply)
+	}
+}
+
+// callKey 以（方法名, 编码后的参数）标识一次调用，参数无法编码时返回 false
+func callKey(method string, args interface{}) (string, bool) {
+	params, ok := args.(json.RawMessage)
+	if !ok {
+		var err error
+		if params, err = json.Marshal(args); err != nil {
+			return "", false
+		}
+	}
+	return method + "\x00" + string(params), true
+}
+
+func (rc *resultCache) get(key string) (json.RawMessage, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expires) {
+		delete(rc.entries, key)
+		return nil, false
+	}
+	return entry.result, true
+}
+
+// set 写入一个缓存项。过期项通常在再次读取同一个 key 时删除，参数各不相同的调用会使过期项不断累积，
+// 因此缓存项达到 sweepAt 时清理所有过期项，并将下一次清理的阈值设为剩余项数的两倍，使清理的开销均摊到每次写入。
+func (rc *resultCache) set(key string, result json.RawMessage) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	now := time.Now()
+	if len(rc.entries) >= rc.sweepAt {
+		for k, entry := range rc.entries {
+			if now.After(entry.expires) {
+				delete(rc.entries, k)
+			}
+		}
+		rc.sweepAt = max(2*len(rc.entries), cacheSweepThreshold)
+	}
+	rc.entries[key] = cacheEntry{result: result, expires: now.Add(rc.ttl)}
+}
+
+// cacheSweepThreshold 是结果缓存清理过期项的最小阈值
+const cacheSweepThreshold = 1024
+
+func (rc *resultCache) delete(key string) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	delete(rc.entries, key)
+}
@@ -0,0 +1,73 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestResultCache(t *testing.T) {
+	s := NewServer()
+	var hits int32
+	s.Handle("get", func(ctx *Context) {
+		atomic.AddInt32(&hits, 1)
+		var k string
+		ctx.Bind(&k)
+		ctx.Result("v-" + k)
+	})
+	s.Handle("fail", func(ctx *Context) {
+		atomic.AddInt32(&hits, 1)
+		ctx.Error(protocol.InternalError(nil))
+	})
+	startServer(t, s)
+	c, err := Dial(s.listener.Addr().String(), WithResultCache(100*time.Millisecond, "get", "fail"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	expectHits := func(want int32) {
+		t.Helper()
+		if n := atomic.LoadInt32(&hits); n != want {
+			t.Fatalf("server handled %d calls, want %d", n, want)
+		}
+	}
+
+	var r string
+	c.Call("get", "a", &r, 5)
+	c.Call("get", "a", &r, 5)
+	if r != "v-a" {
+		t.Fatalf("reply %q", r)
+	}
+	expectHits(1)
+	c.Call("get", "b", &r, 5)
+	if r != "v-b" {
+		t.Fatalf("reply %q", r)
+	}
+	expectHits(2)
+	time.Sleep(120 * time.Millisecond)
+	c.Call("get", "a", &r, 5)
+	expectHits(3)
+	// 错误响应不会被缓存
+	c.Call("fail", nil, nil, 5)
+	c.Call("fail", nil, nil, 5)
+	expectHits(5)
+}
+
+func TestResultCacheSweepsExpiredEntries(t *testing.T) {
+	rc := newResultCache(time.Millisecond, nil)
+	for i := 0; i < cacheSweepThreshold; i++ {
+		rc.set(fmt.Sprintf("k%d", i), json.RawMessage("1"))
+	}
+	time.Sleep(5 * time.Millisecond)
+	rc.set("fresh", json.RawMessage("1"))
+	if n := len(rc.entries); n != 1 {
+		t.Fatalf("cache holds %d entries after sweep, want 1", n)
+	}
+	if _, ok := rc.get("fresh"); !ok {
+		t.Fatal("fresh entry was swept")
+	}
+}
@@ -0,0 +1,236 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+)
+
+// chunkMethod 是分块结果通知的方法名
+const chunkMethod = "$/chunk"
+
+// maxChunkSize 是单条分块通知携带的最大字节数，更大的写入会被拆分为多条通知
+const maxChunkSize = 32 << 10
+
+// chunkParams 是分块通知的 params 结构，id 为所属请求的原始 id，chunk 以 base64 编码。
+// chunk 为空的通知是结束标记，表示服务端已写完全部结果。
+type chunkParams struct {
+	ID    json.RawMessage `json:"id"`
+	Chunk []byte          `json:"chunk"`
+}
+
+// ResultWriter 返回一个以分块方式写出结果的 io.WriteCloser，适用于增量计算的大结果。
+// 每次写入作为 $/chunk 通知发送，params 为 {"id": 请求 id, "chunk": base64 数据}；
+// Close 发送 chunk 为空的结束标记。之后最终响应照常写回，其 result 通常为 null，仅用于结束调用和报告错误，
+// 客户端通过 CallStream 读取。分块通知与响应共用连接的写锁，因此总是先于最终响应到达。
+// 通知请求、Copy 得到的副本以及 ServeTransport 的连接不支持分块结果，写入会返回错误。
+func (c *Context) ResultWriter() io.WriteCloser {
+	return &chunkWriter{ctx: c}
+}
+
+// chunkWriter 是 ctx.ResultWriter 返回的写入器
+type chunkWriter struct {
+	ctx    *Context
+	closed bool
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("jsonrpc2: write to closed result writer")
+	}
+	written := 0
+	for len(p) > 0 {
+		n := len(p)
+		if n > maxChunkSize {
+			n = maxChunkSize
+		}
+		if err := w.send(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		p = p[n:]
+	}
+	return written, nil
+}
+
+// Close 发送结束标记，重复调用无效
+func (w *chunkWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.send([]byte{})
+}
+
+func (w *chunkWriter) send(chunk []byte) error {
+	c := w.ctx
+	if c.detached {
+		return errors.New("jsonrpc2: result writer requires the original context")
+	}
+	if len(c.Request.ID) == 0 {
+		return errors.New("jsonrpc2: result writer requires a request id")
+	}
+	if c.sconn == nil {
+		return errors.New("jsonrpc2: result writer requires a connection")
+	}
+	raw, err := json.Marshal(chunkParams{ID: c.Request.ID, Chunk: chunk})
+	if err != nil {
+		return err
+	}
+	return c.sconn.notify(chunkMethod, raw)
+}
+
+// CallStream 发起一个调用并以 io.ReadCloser 读取服务端通过 ctx.ResultWriter 分块写出的结果。
+// 读到全部数据后 Read 返回 io.EOF；调用失败时先返回已收到的数据，再返回该错误；
+// 服务端没有发送结束标记就结束调用时返回 io.ErrUnexpectedEOF。
+// 调用受 ctx 的取消与期限控制，在读完之前 Close 会取消调用。
+// 分块在接收循环中被放入内存缓冲，读取过慢不会阻塞同一连接上的其他调用，但会占用内存。
+func (c *Client) CallStream(ctx context.Context, method string, args interface{}) (io.ReadCloser, error) {
+	id := c.nextID()
+	idKey, err := idToKey(id)
+	if err != nil {
+		return nil, err
+	}
+	r := &chunkReader{cancel: func() { c.Cancel(id) }}
+	r.cond = sync.NewCond(&r.mu)
+	c.mutex.Lock()
+	if c.chunkReaders == nil {
+		c.chunkReaders = make(map[string]*chunkReader)
+	}
+	c.chunkReaders[idKey] = r
+	c.mutex.Unlock()
+
+	call := &Call{
+		Method: method,
+		Args:   args,
+		Meta:   MetaFromContext(ctx),
+		Done:   make(chan *Call, 1),
+	}
+	c.sendContext(ctx, id, call)
+
+	go func() {
+		err := c.awaitCall(ctx, id, call)
+		c.mutex.Lock()
+		delete(c.chunkReaders, idKey)
+		c.mutex.Unlock()
+		r.finish(err)
+	}()
+	return r, nil
+}
+
+// awaitCall 等待流式调用结束。ctx 先结束时取消调用并等待其完成，因取消而结束的调用
+// 返回 ErrTimeout（期限已过）或 ctx.Err()，其他情况返回调用本身的错误。
+func (c *Client) awaitCall(ctx context.Context, id interface{}, call *Call) error {
+	select {
+	case <-call.Done:
+		return call.Error
+	case <-ctx.Done():
+		c.Cancel(id)
+		<-call.Done
+		if errors.Is(call.Error, ErrCancelled) {
+			return contextError(ctx.Err())
+		}
+		return call.Error
+	}
+}
+
+// handleChunk 将分块通知交给对应调用的读取器，返回是否找到了读取器
+func (c *Client) handleChunk(params json.RawMessage) bool {
+	var p struct {
+		ID    interface{} `json:"id"`
+		Chunk []byte      `json:"chunk"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return false
+	}
+	idKey, err := idToKey(p.ID)
+	if err != nil {
+		return false
+	}
+	c.mutex.Lock()
+	r := c.chunkReaders[idKey]
+	c.mutex.Unlock()
+	if r == nil {
+		return false
+	}
+	r.push(p.Chunk)
+	return true
+}
+
+// chunkReader 缓冲收到的分块，供 CallStream 的调用方读取
+type chunkReader struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	chunks [][]byte
+	ended  bool  // 已收到结束标记
+	done   bool  // 调用已结束，不会再有新的分块
+	err    error // 读完缓冲后返回的错误
+	closed bool
+	cancel func()
+}
+
+func (r *chunkReader) push(chunk []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed || r.ended {
+		return
+	}
+	if len(chunk) == 0 {
+		r.ended = true
+	} else {
+		r.chunks = append(r.chunks, chunk)
+	}
+	r.cond.Broadcast()
+}
+
+func (r *chunkReader) finish(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err == nil && !r.ended {
+		err = io.ErrUnexpectedEOF
+	}
+	if err == nil {
+		err = io.EOF
+	}
+	r.done = true
+	r.err = err
+	r.cond.Broadcast()
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for len(r.chunks) == 0 && !r.done && !r.closed {
+		r.cond.Wait()
+	}
+	if r.closed {
+		return 0, io.ErrClosedPipe
+	}
+	if len(r.chunks) == 0 {
+		return 0, r.err
+	}
+	n := copy(p, r.chunks[0])
+	if r.chunks[0] = r.chunks[0][n:]; len(r.chunks[0]) == 0 {
+		r.chunks[0] = nil
+		r.chunks = r.chunks[1:]
+	}
+	return n, nil
+}
+
+// Close 丢弃尚未读取的数据，调用仍未结束时取消调用
+func (r *chunkReader) Close() error {
+	r.mu.Lock()
+	done := r.done
+	if !r.closed {
+		r.closed = true
+		r.chunks = nil
+		r.cond.Broadcast()
+	}
+	r.mu.Unlock()
+	if !done {
+		r.cancel()
+	}
+	return nil
+}
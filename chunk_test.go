@@ -0,0 +1,74 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestResultWriter(t *testing.T) {
+	s := NewServer()
+	data := make([]byte, 3<<20+17)
+	for i := range data {
+		data[i] = byte(i * 7)
+	}
+	s.Handle("big", func(ctx *Context) {
+		w := ctx.ResultWriter()
+		for off := 0; off < len(data); off += 100000 {
+			end := off + 100000
+			if end > len(data) {
+				end = len(data)
+			}
+			if _, err := w.Write(data[off:end]); err != nil {
+				ctx.Fail(err)
+				return
+			}
+		}
+		w.Close()
+	})
+	s.Handle("broken", func(ctx *Context) {
+		ctx.ResultWriter().Write([]byte("partial"))
+		ctx.Error(protocol.InternalError("boom"))
+	})
+	s.Handle("noclose", func(ctx *Context) {
+		ctx.ResultWriter().Write([]byte("x"))
+	})
+	s.Handle("ok", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+
+	rc, err := c.CallStream(context.Background(), "big", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(rc)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("big: %v, read %d of %d bytes", err, len(got), len(data))
+	}
+	rc.Close()
+
+	// 写出部分数据后返回错误：读到已写出的数据，然后得到调用的错误
+	rc, _ = c.CallStream(context.Background(), "broken", nil)
+	got, err = io.ReadAll(rc)
+	if string(got) != "partial" || err == nil {
+		t.Fatalf("broken: read %q, %v", got, err)
+	}
+	// 处理器没有调用 Close 就返回时流不完整
+	rc, _ = c.CallStream(context.Background(), "noclose", nil)
+	if _, err = io.ReadAll(rc); err != io.ErrUnexpectedEOF {
+		t.Fatalf("noclose: got %v, want io.ErrUnexpectedEOF", err)
+	}
+
+	var r int
+	if err := c.Call("ok", nil, &r, 5); err != nil || r != 1 {
+		t.Fatalf("ok: %v", err)
+	}
+	c.mutex.Lock()
+	n := len(c.chunkReaders)
+	c.mutex.Unlock()
+	if n != 0 {
+		t.Fatalf("%d chunk readers left after the calls finished", n)
+	}
+}
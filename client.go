@@ -1,11 +1,16 @@
 package jsonrpc2
 
 import (
+	"bufio"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"math"
 	"net"
+	"reflect"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,87 +19,324 @@ import (
 
 // Call 代表一个挂起的 RPC 调用。
 type Call struct {
+	ID     interface{} // 请求 ID，发送时设置，可传给 Cancel
 	Method string
 	Args   interface{}
 	Reply  interface{}
+	Meta   map[string]string // 随请求发送的元数据，可为空
 	Error  error
 	Done   chan *Call
+
+	// ResponseMeta 是响应中 meta 成员携带的元数据，服务端未设置时为空
+	ResponseMeta map[string]string
 }
 
 type Client struct {
 	conn    net.Conn
-	encoder *json.Encoder
+	encoder messageEncoder
 
 	sendMutex sync.Mutex // 保护对 conn 的写入
 	mutex     sync.Mutex // 保护 Client 内部状态 (seq, pending, closing, shutdown)
 	seq       uint64
+	idGen     func() interface{} // 通过 WithIDGenerator 设置的 ID 生成器，为空时使用 seq 自增
 	pending   map[string]*Call
 	closing   bool
 	shutdown  bool
+
+	maxPending       int        // pending 的数量上限，0 表示不限制
+	failOnMaxPending bool       // 达到上限时立即返回错误而不是阻塞
+	pendingCond      *sync.Cond // pending 减少或客户端关闭时通知阻塞的发送方
+
+	interceptors   []Interceptor                           // 通过 Use 添加的调用拦截器
+	notifyHandlers map[string]func(params json.RawMessage) // 通过 OnNotify 注册的通知处理器
+	handlers       map[string]ClientHandler                // 通过 Handle 注册的请求处理器
+
+	progressHandlers map[string]func(value json.RawMessage) // 通过 OnProgress 注册，key 为调用 ID
+	chunkReaders     map[string]*chunkReader                // CallStream 发起的调用，key 为调用 ID
+	serverStreams    map[string]*ServerStream               // CallServerStream 发起的调用，key 为调用 ID
+
+	logger          Logger
+	codec           Codec
+	marshal         MarshalFunc
+	maxMessageBytes int64         // 单条响应的最大字节数，0 表示不限制
+	readTimeout     time.Duration // 读取每条消息的期限，0 表示不限制
+	writeTimeout    time.Duration // 写入每条消息的期限，0 表示不限制
+
+	propagateDeadline bool          // 将调用期限写入请求 meta，参见 WithDeadlinePropagation
+	defaultTimeout    time.Duration // timeout 为 0 的调用使用的超时时间，参见 WithDefaultTimeout
+
+	// 写缓冲，参见 WithWriteBuffer；writeBuf 为 nil 时直接写入连接。flushTimer 与 flushPending 由 sendMutex 保护
+	writeBufferSize int
+	flushDelay      time.Duration
+	writeBuf        *bufio.Writer
+	writers         int32 // 正在写入或等待写锁的 goroutine 数
+	flushTimer      *time.Timer
+	flushPending    bool
 }
 
 // Dial 连接到指定的 RPC 服务器。
-func Dial(addr string) (*Client, error) {
+func Dial(addr string, opts ...DialOption) (*Client, error) {
 	conn, err := net.Dial("tcp", addr)
 	if err != nil {
 		return nil, err
 	}
+	return NewClient(conn, opts...), nil
+}
+
+// DialUnix 连接到监听在 Unix 域套接字 path 上的 RPC 服务器（参见 Server.Serve），适用于本机进程间通信。
+func DialUnix(path string, opts ...DialOption) (*Client, error) {
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, opts...), nil
+}
+
+// NewClient 在调用方已建立的连接上创建客户端并启动接收循环，适用于 TLS、SSH 通道、net.Pipe 等
+// 由调用方负责建立的连接。关闭客户端时会关闭 conn。
+func NewClient(conn net.Conn, opts ...DialOption) *Client {
 	client := &Client{
 		conn:    conn,
-		encoder: json.NewEncoder(conn),
 		pending: make(map[string]*Call),
+		logger:  stdLogger{},
+	}
+	client.pendingCond = sync.NewCond(&client.mutex)
+	for _, opt := range opts {
+		opt.applyClient(client)
+	}
+	if client.writeBuf = client.newWriteBuffer(); client.writeBuf != nil {
+		client.encoder = newEncoder(client.writeBuf, client.codec, client.marshal)
+	} else {
+		client.encoder = newEncoder(conn, client.codec, client.marshal)
 	}
 	go client.receiveLoop()
-	return client, nil
+	return client
+}
+
+// inboundMessage 是客户端收到的一条消息：带有 method 的是服务端发起的请求或通知，否则是响应
+type inboundMessage struct {
+	Method string                `json:"method"`
+	Params json.RawMessage       `json:"params"`
+	Result json.RawMessage       `json:"result"`
+	Error  *protocol.ErrorObject `json:"error"`
+	ID     interface{}           `json:"id"`
+	Meta   map[string]string     `json:"meta"`
 }
 
 // receiveLoop 循环接收服务端的响应。
 func (c *Client) receiveLoop() {
 	var err error
-	var res protocol.Response
-	decoder := json.NewDecoder(c.conn)
+	decoder := newStreamDecoder(c.conn, c.maxMessageBytes, c.codec)
 
 	for err == nil {
+		// 每次循环使用新的消息，避免上一条响应的 Error 残留到本次
+		var res inboundMessage
+		if c.readTimeout > 0 {
+			c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+		}
 		err = decoder.Decode(&res)
 		if err != nil {
+			if errors.Is(err, ErrMessageTooLarge) {
+				// 超限的消息无法继续解析，直接关闭连接
+				c.conn.Close()
+				break
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// 单条损坏的响应不影响其他调用：能识别出 id 时只让对应的调用失败，
+				// 跳过损坏的这一行后继续接收；无法跳过（如 Codec 模式）时才终止接收循环
+				c.logger.Errorf("jsonrpc2: skipping malformed message from server: %v", err)
+				var id interface{}
+				if raw, ok := decoder.recoverID().(json.RawMessage); ok {
+					json.Unmarshal(raw, &id)
+				}
+				c.failCall(id, fmt.Errorf("jsonrpc2: malformed response: %w", err))
+				if decoder.resync() != nil {
+					break
+				}
+				err = nil
+				continue
+			}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && c.codec == nil {
+				// 完整的 JSON 值但不是合法的消息（例如 error 不是对象），解码器已越过这个值
+				c.logger.Errorf("jsonrpc2: skipping invalid message from server: %v", err)
+				if res.Method == "" {
+					c.failCall(res.ID, fmt.Errorf("jsonrpc2: invalid response: %w", err))
+				}
+				err = nil
+				continue
+			}
 			break
 		}
+		if res.Method != "" {
+			c.handleInbound(&res)
+			continue
+		}
 		idKey, errKey := idToKey(res.ID)
 		if errKey != nil {
-			log.Printf("jsonrpc2: unexpected response ID type: %T, value: %v", res.ID, res.ID)
+			c.logger.Errorf("jsonrpc2: unexpected response ID type: %T, value: %v", res.ID, res.ID)
 			continue
 		}
 
-		c.mutex.Lock()
-		call := c.pending[idKey]
-		delete(c.pending, idKey)
-		c.mutex.Unlock()
-
-		if call != nil {
+		if isStreamPartial(res.Meta) {
+			// 服务端流式调用的中间响应，调用仍在等待最终响应
+			c.handleStreamPartial(idKey, res.Result)
+			continue
+		}
+		if call := c.takePending(idKey); call != nil {
+			call.ResponseMeta = res.Meta
 			if res.Error != nil {
+				// 始终保存为 *protocol.ErrorObject，调用方可通过 AsRPCError 读取 Code 与 Data
 				call.Error = res.Error
 			} else {
-				if call.Reply != nil {
-					jsonData, _ := json.Marshal(res.Result)
-					call.Error = json.Unmarshal(jsonData, call.Reply)
-				}
+				// Result 是原始 JSON 字节，直接解析到 Reply 中
+				call.Error = decodeResult(res.Result, call.Reply)
 			}
 			call.Done <- call
 		}
 	}
 
 	// 发生错误，终止所有挂起的调用
+	c.failPending(err)
+}
+
+// failPending 将客户端标记为已停止，并以 err 结束所有挂起的调用。调用在锁内从 pending 中移除，
+// 与接收循环、Cancel 和发送失败的处理互斥，因此每个调用只会被结束一次。
+func (c *Client) failPending(err error) {
 	c.mutex.Lock()
 	c.shutdown = true
+	calls := make([]*Call, 0, len(c.pending))
 	for key, call := range c.pending {
+		calls = append(calls, call)
+		delete(c.pending, key)
+		delete(c.progressHandlers, key)
+	}
+	c.pendingCond.Broadcast()
+	c.mutex.Unlock()
+	for _, call := range calls {
 		call.Error = err
 		call.Done <- call
-		delete(c.pending, key)
 	}
+}
+
+// failCall 以 err 结束 id 对应的挂起调用，id 无法识别或没有对应的调用时不做任何事
+func (c *Client) failCall(id interface{}, err error) {
+	if id == nil {
+		return
+	}
+	idKey, errKey := idToKey(id)
+	if errKey != nil {
+		return
+	}
+	if call := c.takePending(idKey); call != nil {
+		call.Error = err
+		call.Done <- call
+	}
+}
+
+// takePending 取出并移除 idKey 对应的挂起调用，不存在时返回 nil
+func (c *Client) takePending(idKey string) *Call {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	call := c.pending[idKey]
+	delete(c.pending, idKey)
+	delete(c.progressHandlers, idKey)
+	c.pendingCond.Broadcast()
+	return call
+}
+
+// decodeResult 将原始结果解析到 reply 中，对象、数组与标量（数字、字符串、布尔值）结果均可直接解析到
+// 对应类型的指针。reply 为 nil 或结果缺失时忽略结果，结果为 null 时 reply 保持不变。
+// reply 不是非空指针，或结果的 JSON 类型与 reply 不兼容（例如把字符串解析到 *int）时返回明确的错误。
+func decodeResult(result json.RawMessage, reply interface{}) error {
+	if reply == nil || result == nil {
+		return nil
+	}
+	err := json.Unmarshal(result, reply)
+	if err == nil {
+		return nil
+	}
+	var invalid *json.InvalidUnmarshalError
+	if errors.As(err, &invalid) {
+		return fmt.Errorf("jsonrpc2: reply must be a non-nil pointer, got %T", reply)
+	}
+	return fmt.Errorf("jsonrpc2: cannot decode result into %T: %w", reply, err)
+}
+
+// OnNotify 为服务端推送的指定方法的通知注册处理器，重复注册会替换之前的处理器。
+// 处理器在接收循环中按到达顺序同步调用，不应长时间阻塞。
+func (c *Client) OnNotify(method string, handler func(params json.RawMessage)) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.notifyHandlers == nil {
+		c.notifyHandlers = make(map[string]func(params json.RawMessage))
+	}
+	c.notifyHandlers[method] = handler
+}
+
+// ClientHandler 处理服务端发起的请求或通知。对于请求，返回值会作为响应发回服务端：
+// 返回的 error 按 ctx.Fail 的规则转换：*protocol.ErrorObject 原样返回，实现了 Coder 的错误使用其错误码，
+// 否则包装为 InternalError。
+type ClientHandler func(params json.RawMessage) (interface{}, error)
+
+// Handle 为服务端发起的请求与通知注册处理器，使连接成为双向的。
+// 通知在接收循环中按顺序同步处理，请求在独立的 goroutine 中处理以便处理器内部可以再发起调用。
+// 对于通知，OnNotify 注册的处理器优先于 Handle 注册的处理器。
+func (c *Client) Handle(method string, handler ClientHandler) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.handlers == nil {
+		c.handlers = make(map[string]ClientHandler)
+	}
+	c.handlers[method] = handler
+}
+
+// handleInbound 分发服务端发起的请求或通知，未注册方法的通知会被丢弃，请求则返回 MethodNotFoundError
+func (c *Client) handleInbound(msg *inboundMessage) {
+	if msg.Method == progressMethod && msg.ID == nil && c.handleProgress(msg.Params) {
+		return
+	}
+	if msg.Method == chunkMethod && msg.ID == nil && c.handleChunk(msg.Params) {
+		return
+	}
+
+	c.mutex.Lock()
+	notifyHandler := c.notifyHandlers[msg.Method]
+	handler := c.handlers[msg.Method]
 	c.mutex.Unlock()
+
+	if msg.ID == nil {
+		if notifyHandler != nil {
+			notifyHandler(msg.Params)
+		} else if handler != nil {
+			handler(msg.Params)
+		}
+		return
+	}
+
+	go func() {
+		if handler == nil {
+			c.reply(msg.ID, protocol.MethodNotFoundError(msg.Method))
+			return
+		}
+		result, err := handler(msg.Params)
+		if err != nil {
+			c.reply(msg.ID, toErrorObject(err))
+			return
+		}
+		c.reply(msg.ID, result)
+	}()
 }
 
-// Close 关闭客户端连接。
+// reply 向服务端发回对其请求的响应
+func (c *Client) reply(id interface{}, data interface{}) {
+	if err := c.write(createResponse(id, data)); err != nil {
+		c.logger.Errorf("jsonrpc2: failed to write response: %v", err)
+	}
+}
+
+// Close 关闭客户端连接。仍在等待响应的调用立即以 ErrClientClosed 结束，不必等待接收循环退出。
 func (c *Client) Close() error {
 	c.mutex.Lock()
 	if c.closing {
@@ -102,47 +344,145 @@ func (c *Client) Close() error {
 		return errors.New("client is closing")
 	}
 	c.closing = true
+	c.pendingCond.Broadcast()
 	c.mutex.Unlock()
-	return c.conn.Close()
+	err := c.closeConn()
+	c.failPending(ErrClientClosed)
+	return err
 }
 
-// Call 发起一个同步调用，使用内部自增 ID。
-func (c *Client) Call(method string, args, reply interface{}, timeout time.Duration) error {
+// Shutdown 优雅地关闭客户端：立即停止接受新的调用，等待已发出的调用全部完成后再关闭连接。
+// 若 ctx 先于调用完成而结束，则直接关闭连接（剩余调用以连接错误结束）并返回 ctx.Err()。
+func (c *Client) Shutdown(ctx context.Context) error {
 	c.mutex.Lock()
-	c.seq++
-	seqID := c.seq
+	if c.closing {
+		c.mutex.Unlock()
+		return errors.New("client is closing")
+	}
+	c.closing = true
+	c.pendingCond.Broadcast()
 	c.mutex.Unlock()
 
-	// 调用新的底层 CallWithID 方法
-	return c.CallWithID(seqID, method, args, reply, timeout)
+	ticker := time.NewTicker(10 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		if c.pendingCount() == 0 {
+			return c.closeConn()
+		}
+		select {
+		case <-ctx.Done():
+			c.conn.Close()
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
 }
 
-// Go 发起一个异步调用，使用内部自增 ID。
-func (c *Client) Go(method string, args, reply interface{}, done chan *Call) *Call {
+// pendingCount 返回尚未收到响应的调用数量
+func (c *Client) pendingCount() int {
 	c.mutex.Lock()
-	c.seq++
-	seqID := c.seq
-	c.mutex.Unlock()
+	defer c.mutex.Unlock()
+	return len(c.pending)
+}
+
+// Call 发起一个同步调用，使用内部自增 ID。timeout 以秒计，为 0 时使用 WithDefaultTimeout 设置的默认超时。
+func (c *Client) Call(method string, args, reply interface{}, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.callTimeout(timeout))
+	defer cancel()
+	return c.CallContext(ctx, method, args, reply)
+}
 
+// Invoke 发起一个同步调用并返回未经解析的原始结果 JSON，适用于代理、网关等需要延迟解析或直接转发结果的场景。
+// 服务端返回的错误仍以 *protocol.ErrorObject 的形式返回。
+func (c *Client) Invoke(method string, args interface{}, timeout time.Duration) (json.RawMessage, error) {
+	var result json.RawMessage
+	if err := c.Call(method, args, &result, timeout); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// CallRaw 发起一个同步调用，params 作为已编码好的 JSON 直接写入请求的 params 成员，不会再次编码。
+// 适用于网关、代理等已经持有原始参数字节的场景。
+func (c *Client) CallRaw(method string, params json.RawMessage, reply interface{}, timeout time.Duration) error {
+	return c.Call(method, params, reply, timeout)
+}
+
+// CallJSON 以 JSON 字符串传入参数并以 JSON 字符串返回结果，不做任何 Go 结构体的编解码，便于命令行工具和脚本使用。
+// paramsJSON 必须是 JSON 对象或数组，为空表示不带参数；不合法时在发送前返回错误。
+func (c *Client) CallJSON(method string, paramsJSON string, timeout time.Duration) (string, error) {
+	var params json.RawMessage
+	if trimmed := strings.TrimSpace(paramsJSON); trimmed != "" {
+		if !json.Valid([]byte(trimmed)) || (trimmed[0] != '{' && trimmed[0] != '[') {
+			return "", fmt.Errorf("jsonrpc2: params must be a JSON object or array: %q", paramsJSON)
+		}
+		params = json.RawMessage(trimmed)
+	}
+	var result json.RawMessage
+	if err := c.CallRaw(method, params, &result, timeout); err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// Go 发起一个异步调用，使用内部自增 ID。
+func (c *Client) Go(method string, args, reply interface{}, done chan *Call) *Call {
 	// 调用新的底层 GoWithID 方法
-	return c.GoWithID(seqID, method, args, reply, done)
+	return c.GoWithID(c.nextID(), method, args, reply, done)
+}
+
+// CallWithMeta 发起一个携带元数据的同步调用，使用内部自增 ID。
+// meta 会放入请求的 meta 成员中，服务端可通过 ctx.Meta 读取。
+func (c *Client) CallWithMeta(meta map[string]string, method string, args, reply interface{}, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.callTimeout(timeout))
+	defer cancel()
+	return c.CallContext(ContextWithMeta(ctx, meta), method, args, reply)
 }
 
 // CallWithID 发起一个同步调用，允许用户指定请求 ID。
 func (c *Client) CallWithID(id interface{}, method string, args, reply interface{}, timeout time.Duration) error {
-	// 默认超时时间
-	if timeout.Seconds() == 0 {
-		timeout = 5 * time.Second
+	ctx, cancel := context.WithTimeout(context.Background(), c.callTimeout(timeout))
+	defer cancel()
+	call := &Call{
+		Method: method,
+		Args:   args,
+		Reply:  reply,
+		Done:   make(chan *Call, 1),
 	}
-	call := c.GoWithID(id, method, args, reply, make(chan *Call, 1))
+	c.sendContext(ctx, id, call)
+	return c.wait(ctx, call)
+}
+
+// wait 等待调用完成或 ctx 结束。超时的调用从 pending 中移除，之后到达的响应不会再写入 Reply。
+func (c *Client) wait(ctx context.Context, call *Call) error {
 	select {
 	case <-call.Done:
 		return call.Error
-	case <-time.After(timeout * time.Second):
-		return errors.New("jsonrpc2: call timeout")
+	case <-ctx.Done():
+		if !c.forget(call.ID, call) {
+			// 响应已被取走或调用已被结束，等待其完成
+			<-call.Done
+			return call.Error
+		}
+		return contextError(ctx.Err())
+	}
+}
+
+// callTimeout 将调用方传入的超时参数换算为实际等待时长：非零的 timeout 以秒计，
+// 为 0 时使用 WithDefaultTimeout 设置的时长，未设置时为 5 秒。
+func (c *Client) callTimeout(timeout time.Duration) time.Duration {
+	if timeout != 0 {
+		return timeout * time.Second
+	}
+	if c.defaultTimeout > 0 {
+		return c.defaultTimeout
 	}
+	return defaultCallTimeout
 }
 
+// defaultCallTimeout 是没有设置 WithDefaultTimeout 时 timeout 为 0 的调用的超时时间
+const defaultCallTimeout = 5 * time.Second
+
 // GoWithID 发起一个异步调用，允许用户指定请求 ID。
 func (c *Client) GoWithID(id interface{}, method string, args, reply interface{}, done chan *Call) *Call {
 	if done == nil {
@@ -159,6 +499,34 @@ func (c *Client) GoWithID(id interface{}, method string, args, reply interface{}
 	return call
 }
 
+// WaitAll 阻塞直到所有调用完成，并按传入顺序返回这些调用。
+// 它会从每个调用的 Done 通道各取走一个值；多个调用共享同一通道时，该通道上不应有其他调用。
+func WaitAll(calls ...*Call) []*Call {
+	for _, call := range calls {
+		<-call.Done
+	}
+	return calls
+}
+
+// WaitAny 阻塞直到任意一个调用完成并返回它；未传入调用时返回 nil。
+// 它只从 Done 通道取走一个值，其余调用仍可继续通过 WaitAny、WaitAll 或各自的 Done 等待。
+func WaitAny(calls ...*Call) *Call {
+	cases := make([]reflect.SelectCase, 0, len(calls))
+	seen := make(map[chan *Call]bool, len(calls))
+	for _, call := range calls {
+		if seen[call.Done] {
+			continue
+		}
+		seen[call.Done] = true
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(call.Done)})
+	}
+	if len(cases) == 0 {
+		return nil
+	}
+	_, v, _ := reflect.Select(cases)
+	return v.Interface().(*Call)
+}
+
 // Ping 用于检测客户端连接是否仍然活跃。
 func (c *Client) Ping() bool {
 	var reply string // 期望收到 "pong"
@@ -174,18 +542,121 @@ func (c *Client) Ping() bool {
 	return false
 }
 
+// Discover 调用服务端的 rpc.discover，返回其已注册的方法名列表。
+// 服务端没有该方法时返回 ErrDiscoveryUnsupported，其他错误原样返回。
+func (c *Client) Discover() ([]string, error) {
+	var methods []string
+	err := c.Call("rpc.discover", nil, &methods, 5)
+	if rpcErr, ok := AsRPCError(err); ok && rpcErr.Code == protocol.CodeMethodNotFound {
+		return nil, ErrDiscoveryUnsupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	return methods, nil
+}
+
+// Notify 发送一个通知（不带 id 的请求），服务端不会返回响应。
+func (c *Client) Notify(method string, args interface{}) error {
+	if c.isClosed() {
+		return ErrShutdown
+	}
+	params, err := marshalValue(c.marshal, args)
+	if err != nil {
+		return err
+	}
+	req := &protocol.Request{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+	}
+	return c.write(req)
+}
+
+// write 串行化地向连接写入一条消息
+func (c *Client) write(v interface{}) error {
+	if c.writeBuf != nil {
+		return c.writeBuffered(v)
+	}
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	return writeMessage(c.conn, c.encoder, c.writeTimeout, v)
+}
+
+// isClosed 报告客户端是否已关闭或连接已断开。
+func (c *Client) isClosed() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.shutdown || c.closing
+}
+
+// nextID 生成下一个请求 ID：设置了 ID 生成器时使用生成器，否则使用自增序号。
+func (c *Client) nextID() interface{} {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.idGen != nil {
+		return c.idGen()
+	}
+	c.seq++
+	return c.seq
+}
+
 // send 是一个底层的发送函数，处理所有类型的 ID。
 func (c *Client) send(id interface{}, call *Call) {
+	c.sendContext(context.Background(), id, call)
+}
+
+// sendContext 与 send 相同，但达到 WithMaxPending 上限而阻塞等待时，ctx 结束会使调用以
+// ErrTimeout（期限已过）或 ctx.Err() 结束，不再等待空位。
+func (c *Client) sendContext(ctx context.Context, id interface{}, call *Call) {
 	if id == nil {
 		call.Error = errors.New("jsonrpc2: request id cannot be null for a call that expects a reply")
 		call.Done <- call
 		return
 	}
 
+	call.ID = id
+
+	// 参数在登记 pending 之前编码，编码失败时调用直接以该错误结束；已编码好的参数原样发送
+	params, ok := call.Args.(json.RawMessage)
+	if !ok {
+		var err error
+		if params, err = marshalValue(c.marshal, call.Args); err != nil {
+			call.Error = fmt.Errorf("jsonrpc2: failed to marshal params: %w", err)
+			call.Done <- call
+			return
+		}
+	}
+
 	c.mutex.Lock()
+	// 达到 pending 上限时阻塞等待或直接失败，检查与插入在同一把锁内完成
+	if c.maxPending > 0 && len(c.pending) >= c.maxPending && !c.shutdown && !c.closing {
+		if c.failOnMaxPending {
+			c.mutex.Unlock()
+			call.Error = ErrTooManyPending
+			call.Done <- call
+			return
+		}
+		// ctx 结束时唤醒等待者；持锁广播，保证不会错过在检查 ctx.Err() 与 Wait 之间发生的取消
+		stop := context.AfterFunc(ctx, func() {
+			c.mutex.Lock()
+			c.pendingCond.Broadcast()
+			c.mutex.Unlock()
+		})
+		for len(c.pending) >= c.maxPending && !c.shutdown && !c.closing && ctx.Err() == nil {
+			c.pendingCond.Wait()
+		}
+		stop()
+		if err := ctx.Err(); err != nil && !c.shutdown && !c.closing {
+			c.mutex.Unlock()
+			call.Error = contextError(err)
+			call.Done <- call
+			return
+		}
+	}
 	if c.shutdown || c.closing {
 		c.mutex.Unlock()
-		call.Error = errors.New("client is shut down or closing")
+		call.Error = ErrShutdown
 		call.Done <- call
 		return
 	}
@@ -197,44 +668,91 @@ func (c *Client) send(id interface{}, call *Call) {
 		call.Done <- call
 		return
 	}
+	if _, dup := c.pending[idKey]; dup {
+		c.mutex.Unlock()
+		call.Error = fmt.Errorf("jsonrpc2: duplicate request id %v", id)
+		call.Done <- call
+		return
+	}
 	c.pending[idKey] = call
 	c.mutex.Unlock()
 
-	params, _ := json.Marshal(call.Args)
+	rawID, _ := json.Marshal(id)
 	req := &protocol.Request{
 		Jsonrpc: "2.0",
 		Method:  call.Method,
 		Params:  params,
-		ID:      id,
+		ID:      rawID,
+		Meta:    call.Meta,
 	}
 
-	c.sendMutex.Lock()
-	err = c.encoder.Encode(req)
-	c.sendMutex.Unlock()
+	if err = c.write(req); err != nil {
+		// 写入失败时调用可能已被 Close 或接收循环结束，只有仍由我们移除的调用才在这里结束
+		if c.forget(id, call) {
+			call.Error = err
+			call.Done <- call
+		}
+	}
+}
 
+// cancelRequestMethod 是客户端取消调用时发给服务端的通知方法名
+const cancelRequestMethod = "$/cancelRequest"
+
+// cancelParams 是 $/cancelRequest 通知的参数
+type cancelParams struct {
+	ID interface{} `json:"id"`
+}
+
+// Cancel 取消一个仍在等待响应的调用：将其从 pending 中移除，并以 ErrCancelled 完成该 Call，
+// 之后到达的响应会被丢弃。同时向服务端发送一条 $/cancelRequest 通知（params 为 {"id": id}），
+// 服务端可据此中止处理。返回 false 表示没有该 ID 的等待中调用（已完成、已取消或不存在）。
+func (c *Client) Cancel(id interface{}) bool {
+	idKey, err := idToKey(id)
 	if err != nil {
-		c.mutex.Lock()
-		// 确保我们删除的是同一个 call
-		if c.pending[idKey] == call {
-			delete(c.pending, idKey)
-		}
-		c.mutex.Unlock()
+		return false
+	}
+	c.mutex.Lock()
+	call, ok := c.pending[idKey]
+	if ok {
+		delete(c.pending, idKey)
+		delete(c.progressHandlers, idKey)
+		c.pendingCond.Broadcast()
+	}
+	c.mutex.Unlock()
+	if !ok {
+		return false
+	}
 
-		call.Error = err
-		call.Done <- call
+	call.Error = ErrCancelled
+	call.Done <- call
+	if err := c.Notify(cancelRequestMethod, cancelParams{ID: id}); err != nil {
+		c.logger.Debugf("jsonrpc2: failed to send cancel notification: %v", err)
 	}
+	return true
 }
 
 // idToKey 辅助函数，将各种 ID 类型转换为唯一的字符串 key，用于 map。
+// 支持小数 ID：不同的浮点数映射到不同的 key，整数值的浮点数与对应整数映射到同一个 key。
 func idToKey(id interface{}) (string, error) {
 	switch v := id.(type) {
 	case string:
 		return v, nil
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
 		return fmt.Sprintf("%d", v), nil
-	case float32, float64:
-		return fmt.Sprintf("%.0f", v), nil // 优化点
+	case float32:
+		return floatKey(float64(v), 32), nil
+	case float64:
+		return floatKey(v, 64), nil
 	default:
 		return "", fmt.Errorf("jsonrpc2: unsupported id type '%T' for map key", v)
 	}
 }
+
+// floatKey 将浮点数 ID 格式化为 key。JSON 数字 ID 解码后是 float64，
+// 整数值需要与发送时的整数 ID 格式一致（避免 1e+06 这样的指数形式），其余值保留全部精度。
+func floatKey(v float64, bitSize int) string {
+	if v == math.Trunc(v) && math.Abs(v) < 1<<63 {
+		return strconv.FormatInt(int64(v), 10)
+	}
+	return strconv.FormatFloat(v, 'g', -1, bitSize)
+}
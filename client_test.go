@@ -0,0 +1,496 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestMaxPending(t *testing.T) {
+	s := NewServer()
+	release := make(chan struct{})
+	s.Handle("block", func(ctx *Context) { <-release; ctx.Result(1) })
+	startServer(t, s)
+	addr := s.listener.Addr().String()
+
+	c, err := Dial(addr, WithMaxPending(2), WithFailOnMaxPending())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Go("block", nil, nil, nil)
+	c.Go("block", nil, nil, nil)
+	if r := <-c.Go("block", nil, nil, nil).Done; r.Error != ErrTooManyPending {
+		t.Fatalf("got %v, want ErrTooManyPending", r.Error)
+	}
+
+	c2, err := Dial(addr, WithMaxPending(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	c2.Go("block", nil, nil, nil)
+	third := make(chan *Call, 1)
+	go func() { third <- c2.Go("block", nil, nil, nil) }()
+	select {
+	case <-third:
+		t.Fatal("call should block while pending is saturated")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(release)
+	if r := <-(<-third).Done; r.Error != nil {
+		t.Fatal(r.Error)
+	}
+}
+
+func TestMaxPendingWaitHonoursContext(t *testing.T) {
+	s := NewServer()
+	release := make(chan struct{})
+	defer close(release)
+	s.Handle("block", func(ctx *Context) { <-release; ctx.Result(1) })
+	startServer(t, s)
+
+	c, err := Dial(s.listener.Addr().String(), WithMaxPending(1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.Go("block", nil, nil, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := c.CallContext(ctx, "block", nil, nil); err != ErrTimeout {
+		t.Fatalf("got %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("saturated call returned after %v", elapsed)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	if err := c.CallContext(ctx, "block", nil, nil); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got %v, want context.Canceled", err)
+	}
+
+	// 等待失败的调用没有占用 pending
+	if n := c.pendingCount(); n != 1 {
+		t.Fatalf("pending = %d, want 1", n)
+	}
+}
+
+func TestCallDecodesResultIntoReply(t *testing.T) {
+	type In struct{ A []int }
+	type Out struct {
+		X  In
+		Ok bool
+	}
+	s := NewServer()
+	s.Handle("n", func(ctx *Context) { ctx.Result(Out{In{[]int{1, 2}}, true}) })
+	s.Handle("nil", func(ctx *Context) {})
+	c := startServer(t, s)
+	var o Out
+	if err := c.Call("n", nil, &o, 5); err != nil || len(o.X.A) != 2 || !o.Ok {
+		t.Fatalf("Call = %v, reply %+v", err, o)
+	}
+	p := new(int)
+	if err := c.Call("nil", nil, &p, 5); err != nil || p != nil {
+		t.Fatalf("Call = %v, reply %v, want nil", err, p)
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(map[string]int{"a": 1}) })
+	s.Handle("fail", func(ctx *Context) { ctx.Error(protocol.InternalError(nil)) })
+	c := startServer(t, s)
+	raw, err := c.Invoke("x", nil, 5)
+	if err != nil || string(raw) != `{"a":1}` {
+		t.Fatalf("Invoke = %v, %s", err, raw)
+	}
+	if _, err := c.Invoke("fail", nil, 5); err == nil {
+		t.Fatal("expected an error")
+	} else if _, ok := AsRPCError(err); !ok {
+		t.Fatalf("got %T, want *protocol.ErrorObject", err)
+	}
+}
+
+func TestClientHandlesServerMessages(t *testing.T) {
+	serverSide, clientSide := net.Pipe()
+	defer serverSide.Close()
+	c := NewClient(clientSide)
+	defer c.Close()
+	got := make(chan string, 1)
+	c.OnNotify("ev", func(p json.RawMessage) { got <- string(p) })
+	c.Handle("add", func(p json.RawMessage) (interface{}, error) {
+		var a []int
+		if err := json.Unmarshal(p, &a); err != nil {
+			return nil, err
+		}
+		return a[0] + a[1], nil
+	})
+
+	go serverSide.Write([]byte(`{"jsonrpc":"2.0","method":"ev","params":[1]}` + "\n" +
+		`{"jsonrpc":"2.0","method":"add","params":[1,2],"id":"r1"}` + "\n" +
+		`{"jsonrpc":"2.0","method":"nope","id":2}` + "\n"))
+	if v := <-got; v != "[1]" {
+		t.Fatalf("notification params %s", v)
+	}
+	// 两个请求的响应可能以任意顺序写回
+	dec := json.NewDecoder(serverSide)
+	byID := make(map[interface{}]map[string]interface{})
+	for i := 0; i < 2; i++ {
+		var resp map[string]interface{}
+		if err := dec.Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		byID[resp["id"]] = resp
+	}
+	if resp := byID["r1"]; resp == nil || resp["result"] != float64(3) {
+		t.Fatalf("got %v for add", resp)
+	}
+	if resp := byID[float64(2)]; errorCode(resp) != protocol.CodeMethodNotFound {
+		t.Fatalf("got %v, want MethodNotFound", resp)
+	}
+}
+
+func TestClientShutdownWaitsForPendingCalls(t *testing.T) {
+	s := NewServer()
+	s.Handle("slow", func(ctx *Context) {
+		time.Sleep(100 * time.Millisecond)
+		ctx.Result(1)
+	})
+	c := startServer(t, s)
+	call := c.Go("slow", nil, nil, nil)
+	time.Sleep(10 * time.Millisecond)
+	if err := c.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-call.Done; r.Error != nil {
+		t.Fatalf("pending call failed: %v", r.Error)
+	}
+	if err := c.Call("slow", nil, nil, 5); !errors.Is(err, ErrShutdown) {
+		t.Fatalf("Call after Shutdown = %v, want ErrShutdown", err)
+	}
+}
+
+func TestIDToKey(t *testing.T) {
+	tests := []struct {
+		id   interface{}
+		want string
+	}{
+		{1, "1"},
+		{int64(1000000), "1000000"},
+		{1.0, "1"},
+		{1.5, "1.5"},
+		{float32(2.25), "2.25"},
+		{"abc", "abc"},
+	}
+	for _, tt := range tests {
+		if got, err := idToKey(tt.id); err != nil || got != tt.want {
+			t.Errorf("idToKey(%v) = %q, %v, want %q", tt.id, got, err, tt.want)
+		}
+	}
+	if _, err := idToKey([]int{1}); err == nil {
+		t.Error("idToKey accepted a slice")
+	}
+}
+
+func TestCallWithFractionalIDs(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(ctx.Request.ID) })
+	c := startServer(t, s)
+	for _, id := range []interface{}{1, 1.5, 1000000, float32(2.25)} {
+		var r float64
+		if err := c.CallWithID(id, "x", nil, &r, 5); err != nil {
+			t.Fatalf("id %v: %v", id, err)
+		}
+	}
+}
+
+func TestCallRawSendsParamsVerbatim(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(string(ctx.Request.Params)) })
+	c := startServer(t, s)
+	var r string
+	if err := c.CallRaw("x", json.RawMessage(`{"a":[1,2]}`), &r, 5); err != nil || r != `{"a":[1,2]}` {
+		t.Fatalf("CallRaw = %v, reply %q", err, r)
+	}
+	if err := c.Call("x", json.RawMessage(`[3]`), &r, 5); err != nil || r != `[3]` {
+		t.Fatalf("Call = %v, reply %q", err, r)
+	}
+}
+
+func TestCallMarshalError(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+	err := c.Call("x", make(chan int), nil, 5)
+	if err == nil || !strings.Contains(err.Error(), "marshal params") {
+		t.Fatalf("got %v, want a marshal error", err)
+	}
+	if n := c.pendingCount(); n != 0 {
+		t.Fatalf("pending = %d after a marshal error", n)
+	}
+	// 客户端仍然可用
+	if err := c.Call("x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClientCancel(t *testing.T) {
+	s := NewServer()
+	got := make(chan string, 1)
+	s.Handle("$/cancelRequest", func(ctx *Context) { got <- string(ctx.Request.Params) })
+	s.Handle("slow", func(ctx *Context) { time.Sleep(200 * time.Millisecond); ctx.Result(1) })
+	c := startServer(t, s)
+
+	call := c.Go("slow", nil, nil, make(chan *Call, 1))
+	if !c.Cancel(call.ID) {
+		t.Fatal("Cancel reported no pending call")
+	}
+	if r := <-call.Done; r.Error != ErrCancelled {
+		t.Fatalf("got %v, want ErrCancelled", r.Error)
+	}
+	if p := <-got; p != `{"id":1}` {
+		t.Fatalf("cancel params = %s", p)
+	}
+	if c.Cancel(call.ID) {
+		t.Fatal("second Cancel reported a pending call")
+	}
+	if n := c.pendingCount(); n != 0 {
+		t.Fatalf("pending = %d after cancel", n)
+	}
+	// 迟到的响应被丢弃，连接仍然可用
+	time.Sleep(250 * time.Millisecond)
+	if !c.Ping() {
+		t.Fatal("ping failed after a late response")
+	}
+}
+
+func TestNewClientOverExistingConn(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(5) })
+	a, b := net.Pipe()
+	go s.ServeConn(a)
+	c := NewClient(b)
+	defer c.Close()
+	var r int
+	if err := c.Call("x", nil, &r, 5); err != nil || r != 5 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+}
+
+func TestDialUnix(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rpc.sock")
+	l, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(5) })
+	go s.Serve(l)
+	defer s.Close(context.Background())
+
+	c, err := DialUnix(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var r int
+	if err := c.Call("x", nil, &r, 5); err != nil || r != 5 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+	if _, err := DialUnix(filepath.Join(t.TempDir(), "missing.sock")); err == nil {
+		t.Fatal("expected DialUnix to fail for a missing socket")
+	}
+}
+
+func TestCallDecodesScalarAndArrayResults(t *testing.T) {
+	s := NewServer()
+	s.Handle("int", func(ctx *Context) { ctx.Result(42) })
+	s.Handle("str", func(ctx *Context) { ctx.Result("text") })
+	s.Handle("bool", func(ctx *Context) { ctx.Result(true) })
+	s.Handle("arr", func(ctx *Context) { ctx.Result([]int{1, 2, 3}) })
+	c := startServer(t, s)
+
+	var i int
+	if err := c.Call("int", nil, &i, 5); err != nil || i != 42 {
+		t.Fatalf("int: %v, %d", err, i)
+	}
+	var str string
+	if err := c.Call("str", nil, &str, 5); err != nil || str != "text" {
+		t.Fatalf("str: %v, %q", err, str)
+	}
+	var b bool
+	if err := c.Call("bool", nil, &b, 5); err != nil || !b {
+		t.Fatalf("bool: %v, %v", err, b)
+	}
+	var arr []int
+	if err := c.Call("arr", nil, &arr, 5); err != nil || len(arr) != 3 {
+		t.Fatalf("arr: %v, %v", err, arr)
+	}
+
+	var ute *json.UnmarshalTypeError
+	if err := c.Call("str", nil, &i, 5); !errors.As(err, &ute) {
+		t.Fatalf("got %v, want a *json.UnmarshalTypeError", err)
+	}
+	// reply 不是指针时返回错误，连接仍然可用
+	if err := c.Call("int", nil, i, 5); err == nil {
+		t.Fatal("expected an error for a non-pointer reply")
+	}
+	if !c.Ping() {
+		t.Fatal("ping failed")
+	}
+}
+
+func TestWaitAnyAndWaitAll(t *testing.T) {
+	s := NewServer()
+	s.Handle("sleep", func(ctx *Context) {
+		var ms int
+		ctx.Bind(&ms)
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		ctx.Result(ms)
+	})
+	c := startServer(t, s)
+
+	var a, b, d int
+	slow := c.Go("sleep", 300, &a, nil)
+	fast := c.Go("sleep", 10, &b, nil)
+	mid := c.Go("sleep", 100, &d, nil)
+	if got := WaitAny(slow, fast, mid); got != fast {
+		t.Fatalf("WaitAny returned the call with args %v, want the fastest", got.Args)
+	}
+	if got := WaitAny(slow, mid); got != mid {
+		t.Fatalf("WaitAny returned the call with args %v, want mid", got.Args)
+	}
+	if done := WaitAll(slow); done[0].Error != nil || a != 300 {
+		t.Fatalf("WaitAll = %v, reply %d", done[0].Error, a)
+	}
+
+	// 多个调用共用同一个 done 通道
+	shared := make(chan *Call, 2)
+	x := c.Go("sleep", 20, new(int), shared)
+	y := c.Go("sleep", 10, new(int), shared)
+	for _, call := range WaitAll(x, y) {
+		if call.Error != nil {
+			t.Fatal(call.Error)
+		}
+	}
+	if WaitAny() != nil {
+		t.Fatal("WaitAny with no calls should return nil")
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	s := NewServer()
+	s.Handle("a.b", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+	m, err := c.Discover()
+	if err != nil || len(m) != 4 || m[0] != "a.b" {
+		t.Fatalf("Discover = %v, %v", m, err)
+	}
+	s.Deregister("rpc.discover")
+	if _, err := c.Discover(); err != ErrDiscoveryUnsupported {
+		t.Fatalf("got %v, want ErrDiscoveryUnsupported", err)
+	}
+}
+
+func TestCallJSON(t *testing.T) {
+	s := NewServer()
+	s.Handle("echo", func(ctx *Context) { ctx.Result(ctx.Request.Params) })
+	c := startServer(t, s)
+	for _, tc := range []struct{ in, want string }{
+		{` {"a":1} `, `{"a":1}`},
+		{`[1,"x"]`, `[1,"x"]`},
+		{``, `null`},
+	} {
+		r, err := c.CallJSON("echo", tc.in, 5)
+		if err != nil || r != tc.want {
+			t.Fatalf("CallJSON(%q) = %q, %v, want %q", tc.in, r, err, tc.want)
+		}
+	}
+	if _, err := c.CallJSON("echo", `{"a":`, 5); err == nil {
+		t.Fatal("expected an error for invalid JSON")
+	}
+	if _, err := c.CallJSON("echo", `3`, 5); err == nil {
+		t.Fatal("expected an error for scalar params")
+	}
+}
+
+func TestClientSurvivesMalformedResponses(t *testing.T) {
+	a, b := net.Pipe()
+	c := NewClient(a)
+	defer c.Close()
+	go func() {
+		r := bufio.NewReader(b)
+		for i := 0; i < 3; i++ {
+			r.ReadString('\n')
+		}
+		b.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":{oops}` + "\n"))
+		b.Write([]byte(`{"jsonrpc":"2.0","id":2,"error":"str"}` + "\n"))
+		b.Write([]byte(`{"jsonrpc":"2.0","id":3,"result":"good"}` + "\n"))
+	}()
+	c1 := c.Go("a", nil, new(string), nil)
+	c2 := c.Go("b", nil, new(string), nil)
+	var r string
+	c3 := c.Go("c", nil, &r, nil)
+	WaitAll(c1, c2, c3)
+	if c1.Error == nil || c2.Error == nil {
+		t.Fatalf("malformed responses should fail their calls: %v, %v", c1.Error, c2.Error)
+	}
+	if c3.Error != nil || r != "good" {
+		t.Fatalf("call after malformed responses = %v, reply %q", c3.Error, r)
+	}
+}
+
+func TestCloseDoesNotWaitForPendingCalls(t *testing.T) {
+	s := NewServer()
+	s.Handle("slow", func(ctx *Context) { time.Sleep(200 * time.Millisecond); ctx.Result(1) })
+	s.Handle("fast", func(ctx *Context) { ctx.Result(1) })
+	startServer(t, s)
+	addr := s.listener.Addr().String()
+
+	for round := 0; round < 20; round++ {
+		c, err := Dial(addr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var wg sync.WaitGroup
+		errs := make(chan error, 200)
+		for i := 0; i < 100; i++ {
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				errs <- c.CallContext(context.Background(), "slow", nil, new(int))
+			}()
+			go func() {
+				defer wg.Done()
+				errs <- c.Call("fast", nil, new(int), 5)
+			}()
+		}
+		// 在调用发出的不同阶段关闭
+		time.Sleep(time.Duration(round%5) * time.Millisecond)
+		start := time.Now()
+		c.Close()
+		wg.Wait()
+		if d := time.Since(start); d > 150*time.Millisecond {
+			t.Fatalf("pending calls returned %v after Close", d)
+		}
+		close(errs)
+		for err := range errs {
+			if err != nil && !errors.Is(err, ErrClientClosed) && !strings.Contains(err.Error(), "closed") && !strings.Contains(err.Error(), "closing") {
+				t.Fatalf("unexpected error after Close: %v", err)
+			}
+		}
+	}
+}
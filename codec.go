@@ -0,0 +1,185 @@
+package jsonrpc2
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// Codec 定义了消息在连接上的编码格式，客户端与服务端必须使用相同的 Codec。
+// Decode 每次调用只能从 r 中读取恰好一条消息：r 是连接上带缓冲的读取器（实现了 io.ByteReader），
+// 多读的数据会导致后续消息无法解析。未设置 Codec 时使用按行分隔的 JSON（json.Encoder/json.Decoder）。
+type Codec interface {
+	Encode(w io.Writer, v interface{}) error
+	Decode(r io.Reader, v interface{}) error
+}
+
+// WithCodec 设置 Server 或 Client 使用的 Codec。
+func WithCodec(codec Codec) Option {
+	return sharedOption{
+		server: func(s *Server) { s.codec = codec },
+		client: func(c *Client) { c.codec = codec },
+	}
+}
+
+// MarshalFunc 将一个值编码为 JSON，签名与 json.Marshal 相同。
+type MarshalFunc func(v interface{}) ([]byte, error)
+
+// WithMarshal 替换默认 JSON 编码使用的 json.Marshal，例如使用第三方 JSON 库或自定义的字段标签。
+// 它作用于未设置 Codec 时写出的每条消息，以及客户端的请求参数与服务端推送的通知参数；
+// 消息仍按行分隔，因此 marshal 的输出不能包含换行符（不能缩进）。设置了 Codec 时消息由 Codec 编码，解码不受影响。
+func WithMarshal(marshal MarshalFunc) Option {
+	return sharedOption{
+		server: func(s *Server) { s.marshal = marshal },
+		client: func(c *Client) { c.marshal = marshal },
+	}
+}
+
+// WithoutHTMLEscape 关闭 JSON 编码对 <、>、& 的默认转义（输出为 \u003c 等），使其原样写出，报文更短。
+// 它是基于 WithMarshal 的便捷选项，二者同时设置时后设置的生效。
+func WithoutHTMLEscape() Option {
+	return WithMarshal(marshalNoEscape)
+}
+
+func marshalNoEscape(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// marshalValue 使用 marshal 编码 v，marshal 为空时使用 json.Marshal
+func marshalValue(marshal MarshalFunc, v interface{}) ([]byte, error) {
+	if marshal == nil {
+		return json.Marshal(v)
+	}
+	return marshal(v)
+}
+
+// messageEncoder 向连接写入单条消息，*json.Encoder 即满足该接口
+type messageEncoder interface {
+	Encode(v interface{}) error
+}
+
+// codecEncoder 使用 Codec 向 w 写入消息
+type codecEncoder struct {
+	codec Codec
+	w     io.Writer
+}
+
+func (e codecEncoder) Encode(v interface{}) error {
+	return e.codec.Encode(e.w, v)
+}
+
+// marshalEncoder 使用 WithMarshal 设置的函数编码消息，每条消息后追加换行符
+type marshalEncoder struct {
+	marshal MarshalFunc
+	w       io.Writer
+}
+
+func (e marshalEncoder) Encode(v interface{}) error {
+	if resp, ok := v.(protocol.Response); ok {
+		// Response.MarshalJSON 内部使用 json.Marshal，先展开为普通结构体，使 marshal 作用于整条消息
+		v = responseMessage(resp)
+	}
+	data, err := e.marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = e.w.Write(append(data, '\n'))
+	return err
+}
+
+// responseMessage 返回与 Response.MarshalJSON 输出结构相同、但没有自定义编码方法的值
+func responseMessage(r protocol.Response) interface{} {
+	if r.Error != nil {
+		return struct {
+			Jsonrpc string                `json:"jsonrpc"`
+			Error   *protocol.ErrorObject `json:"error"`
+			ID      interface{}           `json:"id"`
+			Meta    map[string]string     `json:"meta,omitempty"`
+		}{r.Jsonrpc, r.Error, r.ID, r.Meta}
+	}
+	return struct {
+		Jsonrpc string            `json:"jsonrpc"`
+		Result  interface{}       `json:"result"`
+		ID      interface{}       `json:"id"`
+		Meta    map[string]string `json:"meta,omitempty"`
+	}{r.Jsonrpc, r.Result, r.ID, r.Meta}
+}
+
+// newEncoder 根据是否设置了 Codec 与 MarshalFunc 创建对应的消息编码器
+func newEncoder(w io.Writer, codec Codec, marshal MarshalFunc) messageEncoder {
+	if codec != nil {
+		return codecEncoder{codec: codec, w: w}
+	}
+	if marshal != nil {
+		return marshalEncoder{marshal: marshal, w: w}
+	}
+	return json.NewEncoder(w)
+}
+
+// GzipJSONCodec 将每条消息编码为 JSON 后再以独立的 gzip 数据流写出。
+type GzipJSONCodec struct {
+	// MaxDecodedBytes 限制单条消息解压后的字节数，超过时 Decode 返回 ErrMessageTooLarge，
+	// 防止很小的压缩数据解压出巨大的消息。<= 0 时使用 WithMaxMessageBytes 的设置，
+	// 二者都未设置时为 defaultGzipMaxDecodedBytes。
+	MaxDecodedBytes int64
+}
+
+// defaultGzipMaxDecodedBytes 是 GzipJSONCodec 解压后单条消息的默认上限
+const defaultGzipMaxDecodedBytes = 64 << 20
+
+// Encode 实现了 Codec 接口。整条消息先在内存中压缩，再一次性写入 w。
+func (GzipJSONCodec) Encode(w io.Writer, v interface{}) error {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(zw).Encode(v); err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Decode 实现了 Codec 接口，只读取一个 gzip 数据流。
+func (g GzipJSONCodec) Decode(r io.Reader, v interface{}) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	zr.Multistream(false)
+	max := g.MaxDecodedBytes
+	if max <= 0 {
+		max = defaultGzipMaxDecodedBytes
+	}
+	data, err := io.ReadAll(io.LimitReader(zr, max+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > max {
+		return ErrMessageTooLarge
+	}
+	return json.Unmarshal(data, v)
+}
+
+// withMaxMessageBytes 在未设置 MaxDecodedBytes 时使用 WithMaxMessageBytes 的上限
+func (g GzipJSONCodec) withMaxMessageBytes(max int64) Codec {
+	if g.MaxDecodedBytes <= 0 {
+		g.MaxDecodedBytes = max
+	}
+	return g
+}
+
+// messageLimiter 由需要在解码时自行限制消息大小的 Codec 实现，例如解压后才能知道消息大小的 GzipJSONCodec
+type messageLimiter interface {
+	withMaxMessageBytes(max int64) Codec
+}
@@ -0,0 +1,131 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"bytes"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestGzipJSONCodec(t *testing.T) {
+	s := NewServer(WithCodec(GzipJSONCodec{}), WithMaxMessageBytes(1000))
+	s.Handle("add", func(ctx *Context) {
+		var a []int
+		ctx.Bind(&a)
+		ctx.Result(a[0] + a[1])
+	})
+	startServer(t, s)
+	c, err := Dial(s.listener.Addr().String(), WithCodec(GzipJSONCodec{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 0; i < 5; i++ {
+		var r int
+		if err := c.Call("add", []int{i, 2}, &r, 5); err != nil || r != i+2 {
+			t.Fatalf("Call = %v, reply %d", err, r)
+		}
+	}
+	// 并发的调用共用同一个编码流
+	var calls []*Call
+	for i := 0; i < 20; i++ {
+		calls = append(calls, c.Go("add", []int{i, 2}, new(int), nil))
+	}
+	for i, call := range WaitAll(calls...) {
+		if call.Error != nil || *call.Reply.(*int) != i+2 {
+			t.Fatalf("call %d: %v", i, call.Error)
+		}
+	}
+}
+
+func TestWithoutHTMLEscape(t *testing.T) {
+	for _, escape := range []bool{true, false} {
+		var opts []ServerOption
+		if !escape {
+			opts = append(opts, WithoutHTMLEscape())
+		}
+		s := NewServer(opts...)
+		s.Handle("html", func(ctx *Context) { ctx.Result("<b>a & b</b>") })
+		s.Handle("err", func(ctx *Context) { ctx.Error(protocol.InternalError("<i>")) })
+		a, b := net.Pipe()
+		go s.ServeConn(a)
+		r := bufio.NewReader(b)
+		go b.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"html"}` + "\n"))
+		line, _ := r.ReadString('\n')
+		go b.Write([]byte(`{"jsonrpc":"2.0","id":2,"method":"err"}` + "\n"))
+		line2, _ := r.ReadString('\n')
+		b.Close()
+
+		want := `{"jsonrpc":"2.0","result":"<b>a & b</b>","id":1}` + "\n"
+		want2 := `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error","data":"<i>"},"id":2}` + "\n"
+		if escape {
+			want = `{"jsonrpc":"2.0","result":"\u003cb\u003ea \u0026 b\u003c/b\u003e","id":1}` + "\n"
+			want2 = `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error","data":"\u003ci\u003e"},"id":2}` + "\n"
+		}
+		if line != want {
+			t.Fatalf("escape=%v: got %q, want %q", escape, line, want)
+		}
+		if line2 != want2 {
+			t.Fatalf("escape=%v: got %q, want %q", escape, line2, want2)
+		}
+	}
+
+	// 客户端的参数同样不转义
+	s := NewServer()
+	s.Handle("echo", func(ctx *Context) { ctx.Result(string(ctx.Request.Params)) })
+	a, b := net.Pipe()
+	go s.ServeConn(a)
+	c := NewClient(b, WithoutHTMLEscape())
+	defer c.Close()
+	var r string
+	if err := c.Call("echo", []string{"<x>"}, &r, 5); err != nil || r != `["<x>"]` {
+		t.Fatalf("Call = %v, params %s", err, r)
+	}
+}
+
+func TestGzipJSONCodecLimitsDecodedSize(t *testing.T) {
+	// 约 1MB 的请求压缩后只有几 KB，不超过压缩数据的上限，但解压后超过
+	bomb := map[string]interface{}{"jsonrpc": "2.0", "method": "x", "params": []string{strings.Repeat("a", 1<<20)}, "id": 1}
+	var frame bytes.Buffer
+	if err := (GzipJSONCodec{}).Encode(&frame, bomb); err != nil {
+		t.Fatal(err)
+	}
+	const limit = 64 << 10
+	if frame.Len() >= limit {
+		t.Fatalf("compressed frame is %d bytes, want it under the limit", frame.Len())
+	}
+
+	s := NewServer(WithCodec(GzipJSONCodec{}), WithMaxMessageBytes(limit))
+	ran := make(chan struct{}, 1)
+	s.Handle("x", func(ctx *Context) { ran <- struct{}{}; ctx.Result(1) })
+	startServer(t, s)
+	rc := dialRaw(t, s)
+	rc.conn.Write(frame.Bytes())
+	rc.conn.SetReadDeadline(time.Now().Add(time.Second))
+	var resp map[string]interface{}
+	if err := (GzipJSONCodec{}).Decode(bufio.NewReader(rc.conn), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if errorCode(resp) != protocol.CodeInvalidRequest {
+		t.Fatalf("got %v, want an invalid request error", resp)
+	}
+	select {
+	case <-ran:
+		t.Fatal("the oversized request was dispatched")
+	default:
+	}
+
+	// 直接调用 Decode 时按 MaxDecodedBytes 限制，未设置时使用默认上限
+	var v interface{}
+	err := GzipJSONCodec{MaxDecodedBytes: limit}.Decode(bytes.NewReader(frame.Bytes()), &v)
+	if err != ErrMessageTooLarge {
+		t.Fatalf("Decode = %v, want ErrMessageTooLarge", err)
+	}
+	if err := (GzipJSONCodec{}).Decode(bytes.NewReader(frame.Bytes()), &v); err != nil {
+		t.Fatalf("Decode under the default limit = %v", err)
+	}
+}
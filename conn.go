@@ -0,0 +1,175 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// serverConn 保存服务端单个客户端连接的状态
+type serverConn struct {
+	conn      net.Conn
+	encoder   messageEncoder
+	sendMutex sync.Mutex     // 保护对 conn 的写入，避免响应交错
+	requests  sync.WaitGroup // 追踪该连接上正在处理的请求
+	identity  interface{}    // 鉴权握手得到的身份信息
+
+	writeTimeout time.Duration // 写入每条消息的期限，0 表示不限制
+	marshal      MarshalFunc   // 编码通知参数，为空时使用 json.Marshal
+
+	// transport 不为空时连接建立在 Transport 之上，此时 conn 与 encoder 为空
+	transport Transport
+	stop      chan struct{} // 关闭后 Transport 的读循环退出
+	stopOnce  sync.Once
+
+	lastDone chan struct{} // WithOrderedResponses 下上一个请求处理完毕时关闭，仅由读循环访问
+	slots    chan struct{} // WithMaxInFlight 的信号量，由读循环在第一次分发时创建
+	queued   chan struct{} // 等待 slots 的请求数，与 slots 同时创建，满时读循环暂停
+
+	mu            sync.Mutex // 保护 subscriptions、inflight 与 closed
+	subscriptions map[string]*Subscription
+	inflight      map[string]*inflightRequest // 正在处理的请求，key 为原始 id
+	closed        bool
+}
+
+// inflightRequest 记录一个正在处理的请求的取消函数
+type inflightRequest struct {
+	cancel context.CancelFunc
+}
+
+func newServerConn(conn net.Conn, codec Codec, marshal MarshalFunc, writeTimeout time.Duration) *serverConn {
+	return &serverConn{
+		conn:         conn,
+		encoder:      newEncoder(conn, codec, marshal),
+		marshal:      marshal,
+		writeTimeout: writeTimeout,
+	}
+}
+
+func newTransportConn(t Transport) *serverConn {
+	return &serverConn{
+		transport: t,
+		stop:      make(chan struct{}),
+	}
+}
+
+// write 串行化地向连接写入一条消息
+func (sc *serverConn) write(v interface{}) error {
+	sc.sendMutex.Lock()
+	defer sc.sendMutex.Unlock()
+	if sc.transport != nil {
+		// Transport 只能发送响应
+		resp, ok := v.(protocol.Response)
+		if !ok {
+			return errTransportNotify
+		}
+		return sc.transport.Send(&resp)
+	}
+	return writeMessage(sc.conn, sc.encoder, sc.writeTimeout, v)
+}
+
+// writeMessage 在写入期限内编码一条消息，调用方需持有写锁。
+// 写入超时后连接上可能残留半条消息，无法继续使用，因此直接关闭连接。
+func writeMessage(conn net.Conn, encoder messageEncoder, timeout time.Duration, v interface{}) error {
+	if timeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(timeout))
+	}
+	err := encoder.Encode(v)
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		conn.Close()
+	}
+	return err
+}
+
+// notify 向连接写入一条服务端发起的通知
+func (sc *serverConn) notify(method string, params json.RawMessage) error {
+	return sc.write(&protocol.Request{
+		Jsonrpc: "2.0",
+		Method:  method,
+		Params:  params,
+	})
+}
+
+// addSubscription 登记订阅；连接已关闭时返回 false
+func (sc *serverConn) addSubscription(sub *Subscription) bool {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.closed {
+		return false
+	}
+	if sc.subscriptions == nil {
+		sc.subscriptions = make(map[string]*Subscription)
+	}
+	sc.subscriptions[sub.ID] = sub
+	return true
+}
+
+func (sc *serverConn) removeSubscription(id string) {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	delete(sc.subscriptions, id)
+}
+
+// stopReading 让读循环尽快退出，连接保持打开以写回正在处理的请求的响应
+func (sc *serverConn) stopReading() {
+	if sc.transport != nil {
+		sc.stopOnce.Do(func() { close(sc.stop) })
+		return
+	}
+	sc.conn.SetReadDeadline(time.Now())
+}
+
+// trackRequest 登记一个正在处理的请求，返回的函数用于注销
+func (sc *serverConn) trackRequest(id json.RawMessage, cancel context.CancelFunc) (untrack func()) {
+	key := string(id)
+	r := &inflightRequest{cancel: cancel}
+	sc.mu.Lock()
+	if sc.inflight == nil {
+		sc.inflight = make(map[string]*inflightRequest)
+	}
+	sc.inflight[key] = r
+	sc.mu.Unlock()
+	return func() {
+		sc.mu.Lock()
+		// id 重复时只注销自己登记的那一个
+		if sc.inflight[key] == r {
+			delete(sc.inflight, key)
+		}
+		sc.mu.Unlock()
+	}
+}
+
+// cancelRequest 取消 id 对应的正在处理的请求，返回是否找到该请求
+func (sc *serverConn) cancelRequest(id json.RawMessage) bool {
+	sc.mu.Lock()
+	r, ok := sc.inflight[string(id)]
+	sc.mu.Unlock()
+	if ok {
+		r.cancel()
+	}
+	return ok
+}
+
+// close 关闭连接并清理其上的所有订阅
+func (sc *serverConn) close() {
+	sc.mu.Lock()
+	sc.closed = true
+	subs := sc.subscriptions
+	sc.subscriptions = nil
+	sc.mu.Unlock()
+
+	if sc.transport != nil {
+		sc.transport.Close()
+	} else {
+		sc.conn.Close()
+	}
+	for _, sub := range subs {
+		sub.close()
+	}
+}
@@ -1,15 +1,21 @@
 package jsonrpc2
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net"
+	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/kyle-cao/jsonrpc2/protocol"
 )
 
 // Context 封装了单次 RPC 调用的所有信息。
+// Context 由对象池复用，处理链返回后不得继续持有或使用它。
 type Context struct {
 	context.Context
 	Conn       net.Conn
@@ -21,6 +27,61 @@ type Context struct {
 	responseError  *protocol.ErrorObject
 	handlerChain   []HandlerFunc
 	handlerIdx     int
+	aborted        bool
+	validator      Validator
+	detached       bool        // 由 Copy 创建，设置响应的操作无效
+	sconn          *serverConn // 请求所在的连接
+	server         *Server     // 处理请求的服务器，Send 经由它写出中间响应
+	identity       interface{} // 连接鉴权握手得到的身份信息
+	responseMeta   map[string]string
+	afterResponse  []func(writeErr error) // 响应写出后依次调用
+	useNumber      bool                   // 解析 params 时将数字保留为 json.Number
+	rawRequest     []byte                 // 请求的原始字节，参见 RawRequest
+	streamEnded    bool                   // 已调用 End，之后 Send 返回错误
+}
+
+// contextPool 复用 Context 对象，降低高并发下每个请求的内存分配
+var contextPool = sync.Pool{
+	New: func() interface{} {
+		return &Context{handlerIdx: -1}
+	},
+}
+
+// acquireContext 从对象池中取出一个已重置的 Context
+func acquireContext() *Context {
+	return contextPool.Get().(*Context)
+}
+
+// releaseContext 重置 Context 并放回对象池，调用后不得再使用 c
+func releaseContext(c *Context) {
+	c.reset()
+	contextPool.Put(c)
+}
+
+// reset 清空 Context 的所有请求相关状态，store 的底层 map 会被保留以便复用
+func (c *Context) reset() {
+	c.Context = nil
+	c.Conn = nil
+	c.Request = nil
+	c.storeMutex.Lock()
+	clear(c.store)
+	c.storeMutex.Unlock()
+	c.responseResult = nil
+	c.responseError = nil
+	c.handlerChain = nil
+	c.handlerIdx = -1
+	c.aborted = false
+	c.validator = nil
+	c.detached = false
+	c.sconn = nil
+	c.server = nil
+	c.identity = nil
+	c.responseMeta = nil
+	clear(c.afterResponse)
+	c.afterResponse = c.afterResponse[:0]
+	c.useNumber = false
+	c.rawRequest = nil
+	c.streamEnded = false
 }
 
 // Next 调用处理链中的下一个处理器。
@@ -31,6 +92,26 @@ func (c *Context) Next() {
 	}
 }
 
+// Abort 中断处理链，之后的 Next 调用不再执行任何处理器。
+// 已经在执行中的外层中间件仍会在 Next 返回后继续执行其后续逻辑。
+func (c *Context) Abort() {
+	c.handlerIdx = len(c.handlerChain)
+	c.aborted = true
+}
+
+// IsAborted 报告处理链是否已被中断。
+func (c *Context) IsAborted() bool {
+	return c.aborted
+}
+
+// RemoteAddr 返回请求所在连接的远端地址，连接不可用时返回 nil。
+func (c *Context) RemoteAddr() net.Addr {
+	if c.Conn == nil {
+		return nil
+	}
+	return c.Conn.RemoteAddr()
+}
+
 // GetResponseError 获取由处理器设置的错误响应。
 func (c *Context) GetResponseError() *protocol.ErrorObject {
 	return c.responseError
@@ -42,23 +123,314 @@ func (c *Context) GetResponseResult() interface{} {
 }
 
 // Bind 将请求的 Params 解析到指定的结构体指针中。
+// 失败时返回 InvalidParamsError；类型不匹配或语法错误时其 Data 为 FieldError，指出出错的字段及原因。
 func (c *Context) Bind(v interface{}) error {
 	if c.Request.Params == nil {
 		return protocol.InvalidParamsError("params are null")
 	}
-	return json.Unmarshal(c.Request.Params, v)
+	if err := c.unmarshal(c.Request.Params, v); err != nil {
+		return paramsError(err)
+	}
+	return nil
+}
+
+// MustBind 与 Bind 相同，但失败时自动将 InvalidParamsError 设置为响应错误并中断处理链，返回 false，
+// 处理器可以写作 if !ctx.MustBind(&p) { return }。
+func (c *Context) MustBind(v interface{}) bool {
+	if err := c.Bind(v); err != nil {
+		c.Fail(err)
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// FieldError 是解析 params 失败时 InvalidParamsError 的 Data，客户端可据此定位出错的输入，
+// 例如 {"field":"a.b","reason":"expected int, got string"}。
+type FieldError struct {
+	Field  string `json:"field,omitempty"` // 出错字段的点分路径，无法定位到字段（如语法错误）时为空
+	Reason string `json:"reason"`
+}
+
+// paramsError 将解析 params 的错误转换为 InvalidParamsError，能识别的错误使用 FieldError 作为 Data
+func paramsError(err error) *protocol.ErrorObject {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return protocol.InvalidParamsError(FieldError{
+			Field:  typeErr.Field,
+			Reason: fmt.Sprintf("expected %s, got %s", typeErr.Type, typeErr.Value),
+		})
+	}
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		return protocol.InvalidParamsError(FieldError{Reason: syntaxErr.Error()})
+	}
+	msg := strings.TrimPrefix(err.Error(), "json: ")
+	// DisallowUnknownFields 产生的错误没有专门的类型，只能从错误信息中取出字段名
+	if quoted, ok := strings.CutPrefix(msg, "unknown field "); ok {
+		if field, err := strconv.Unquote(quoted); err == nil {
+			return protocol.InvalidParamsError(FieldError{Field: field, Reason: "unknown field"})
+		}
+	}
+	return protocol.InvalidParamsError(msg)
+}
+
+// unmarshal 解析 params，服务端设置了 WithUseNumber 时数字以 json.Number 保存
+func (c *Context) unmarshal(data []byte, v interface{}) error {
+	if !c.useNumber {
+		return json.Unmarshal(data, v)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// BindStrict 与 Bind 相同，但拒绝目标结构体中不存在的字段，
+// 以便尽早发现客户端的拼写错误（例如把 "amount" 写成 "amout"）。
+// 失败时返回 InvalidParamsError，其 Data 为 FieldError，未知字段的 reason 为 "unknown field"。
+func (c *Context) BindStrict(v interface{}) error {
+	if c.Request.Params == nil {
+		return protocol.InvalidParamsError("params are null")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(c.Request.Params))
+	decoder.DisallowUnknownFields()
+	if c.useNumber {
+		decoder.UseNumber()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return paramsError(err)
+	}
+	return nil
+}
+
+// Params 将对象形式（按名称）的 params 解析为 map，适用于代理、通用分发器等没有固定结构体的处理器。
+// 请求没有 params 或 params 为 null 时返回 nil 与 nil；params 不是 JSON 对象时返回 InvalidParamsError。
+func (c *Context) Params() (map[string]interface{}, error) {
+	params := bytes.TrimSpace(c.Request.Params)
+	if len(params) == 0 || string(params) == "null" {
+		return nil, nil
+	}
+	if params[0] != '{' {
+		return nil, protocol.InvalidParamsError("params must be an object")
+	}
+	var m map[string]interface{}
+	if err := c.unmarshal(params, &m); err != nil {
+		return nil, paramsError(err)
+	}
+	return m, nil
+}
+
+// ParamsArray 将数组形式（按位置）的 params 解析为切片。
+// 请求没有 params 或 params 为 null 时返回 nil 与 nil；params 不是 JSON 数组时返回 InvalidParamsError。
+func (c *Context) ParamsArray() ([]interface{}, error) {
+	params := bytes.TrimSpace(c.Request.Params)
+	if len(params) == 0 || string(params) == "null" {
+		return nil, nil
+	}
+	if params[0] != '[' {
+		return nil, protocol.InvalidParamsError("params must be an array")
+	}
+	var a []interface{}
+	if err := c.unmarshal(params, &a); err != nil {
+		return nil, paramsError(err)
+	}
+	return a, nil
+}
+
+// BindScalar 将标量形式的 params（如 "params": 5 或 "params": "text"）解析到 v 中，
+// 用于兼容不按规范把参数放在对象或数组中的简单客户端。
+// 请求没有 params 或 params 为 null 时返回 InvalidParamsError，params 是对象或数组时同样返回 InvalidParamsError。
+func (c *Context) BindScalar(v interface{}) error {
+	params := bytes.TrimSpace(c.Request.Params)
+	if len(params) == 0 || string(params) == "null" {
+		return protocol.InvalidParamsError("params are null")
+	}
+	if params[0] == '{' || params[0] == '[' {
+		return protocol.InvalidParamsError("params must be a scalar")
+	}
+	if err := c.unmarshal(params, v); err != nil {
+		return paramsError(err)
+	}
+	return nil
+}
+
+// BindAndValidate 将请求的 Params 解析到 v 中，并使用服务端配置的校验器进行校验。
+// 解析或校验失败时返回带有详细信息的 InvalidParamsError。
+func (c *Context) BindAndValidate(v interface{}) error {
+	if err := c.Bind(v); err != nil {
+		if rpcErr, ok := err.(*protocol.ErrorObject); ok {
+			return rpcErr
+		}
+		return protocol.InvalidParamsError(err.Error())
+	}
+	if c.validator == nil {
+		return nil
+	}
+	if err := c.validator.Validate(v); err != nil {
+		return protocol.InvalidParamsError(err.Error())
+	}
+	return nil
 }
 
 // Result 设置成功的响应结果。
+// 若同一请求还调用了 Error，错误优先，结果会被丢弃。
 func (c *Context) Result(data interface{}) {
+	if c.detached {
+		return
+	}
 	c.responseResult = data
 }
 
-// Error 设置失败的响应。
+// Error 设置失败的响应。错误优先于 Result 设置的结果。
 func (c *Context) Error(err *protocol.ErrorObject) {
+	if c.detached {
+		return
+	}
 	c.responseError = err
 }
 
+// Fail 将一个 Go error 设置为失败的响应：*protocol.ErrorObject 原样使用，
+// 实现了 Coder 接口的错误使用其错误码，其余错误包装为 InternalError，错误信息放在 data 中。
+// err 为 nil 时不做任何事。
+func (c *Context) Fail(err error) {
+	if err == nil {
+		return
+	}
+	c.Error(toErrorObject(err))
+}
+
+// Forward 将当前请求原样转发给 upstream 并转交其响应，适用于网关与代理。
+// 方法名、原始 params 字节与请求 meta 都会随请求发出，调用受 ctx 的取消与期限控制；
+// 上游的结果以原始字节设置为 ctx.Result，不会重新解码。上游返回的错误对象原样设置为响应错误，
+// 其余错误（例如连接断开）按 ctx.Fail 的规则设置，两种情况都会返回该错误。
+func (c *Context) Forward(upstream *Client) error {
+	var result json.RawMessage
+	err := upstream.CallContext(ContextWithMeta(c, c.Request.Meta), c.Request.Method, c.Request.Params, &result)
+	if err != nil {
+		c.Fail(err)
+		return err
+	}
+	c.Result(result)
+	return nil
+}
+
+// Copy 返回一个可以在其他 goroutine 中安全使用的 Context 副本。
+// 副本持有请求与 store 的快照，不引用连接与处理链，对其设置响应结果或错误不会产生任何效果。
+// 处理器需要在返回后继续进行后台工作时，应当使用副本而不是原始 Context。
+// 副本保留原 context 中的值，但不会随处理器返回或请求被取消而取消，也不继承原请求的期限。
+func (c *Context) Copy() *Context {
+	cp := &Context{
+		handlerIdx: -1,
+		validator:  c.validator,
+		detached:   true,
+		identity:   c.identity,
+		useNumber:  c.useNumber,
+		rawRequest: c.rawRequest,
+	}
+	if c.Context != nil {
+		cp.Context = context.WithoutCancel(c.Context)
+	}
+	if c.Request != nil {
+		req := *c.Request
+		req.Params = append([]byte(nil), c.Request.Params...)
+		req.ID = append(json.RawMessage(nil), c.Request.ID...)
+		if c.Request.Meta != nil {
+			req.Meta = make(map[string]string, len(c.Request.Meta))
+			for k, v := range c.Request.Meta {
+				req.Meta[k] = v
+			}
+		}
+		cp.Request = &req
+	}
+	c.storeMutex.RLock()
+	if len(c.store) > 0 {
+		cp.store = make(map[string]interface{}, len(c.store))
+		for k, v := range c.store {
+			cp.store[k] = v
+		}
+	}
+	c.storeMutex.RUnlock()
+	return cp
+}
+
+// Meta 读取请求 meta 成员中指定 key 的元数据。
+func (c *Context) Meta(key string) (string, bool) {
+	value, ok := c.Request.Meta[key]
+	return value, ok
+}
+
+// SetResponseMeta 在响应的 meta 成员中设置一项元数据，客户端可通过 ContextWithCallMeta 读取。
+// 通过 ctx.Stream 写出的响应不携带 meta。
+func (c *Context) SetResponseMeta(key, value string) {
+	if c.detached {
+		return
+	}
+	if c.responseMeta == nil {
+		c.responseMeta = make(map[string]string)
+	}
+	c.responseMeta[key] = value
+}
+
+// AfterResponse 注册一个在响应写出之后调用的回调，writeErr 为写入连接失败时的错误（例如客户端已断开），
+// 成功写出时为 nil。中间件可借此区分处理器错误与投递失败；通知请求没有响应，回调的 writeErr 总是 nil。
+// 回调按注册顺序在处理请求的 goroutine 中调用，此时 Context 仍然有效。
+func (c *Context) AfterResponse(fn func(writeErr error)) {
+	if c.detached {
+		return
+	}
+	c.afterResponse = append(c.afterResponse, fn)
+}
+
+// runAfterResponse 依次调用通过 AfterResponse 注册的回调
+func (c *Context) runAfterResponse(writeErr error) {
+	for _, fn := range c.afterResponse {
+		fn(writeErr)
+	}
+}
+
+// RequestID 返回请求的 id：字符串 id 为 string，数字 id 为 json.Number（保留原始精度），
+// 通知或 id 为 null 时返回 nil。同一个值也可以在下游通过 RequestIDFromContext(ctx) 读取。
+func (c *Context) RequestID() interface{} {
+	if c.Request == nil {
+		return nil
+	}
+	return decodeID(c.Request.ID)
+}
+
+// RawRequest 返回客户端发送的请求报文原文（不含消息之间的空白与换行），供审计、防篡改校验或重放使用。
+// 返回的切片不得修改。设置了 Codec 或通过 ServeTransport 接收的请求无法取得原文，返回 nil。
+func (c *Context) RawRequest() []byte {
+	return c.rawRequest
+}
+
+type requestIDContextKey struct{}
+
+// RequestIDFromContext 读取服务端放入处理器 context 的请求 id，取值规则与 ctx.RequestID 相同。
+// 只接收 context.Context 的下游库可借此关联日志；处理通知或不在请求处理中时返回 nil 和 false。
+func RequestIDFromContext(ctx context.Context) (interface{}, bool) {
+	id := ctx.Value(requestIDContextKey{})
+	return id, id != nil
+}
+
+// decodeID 将原始 id 解码为 string 或 json.Number，缺失或为 null 时返回 nil
+func decodeID(raw json.RawMessage) interface{} {
+	if len(raw) == 0 {
+		return nil
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var id interface{}
+	if decoder.Decode(&id) != nil {
+		return nil
+	}
+	return id
+}
+
+// Identity 返回连接鉴权握手（WithAuthHandshake）得到的身份信息，未配置握手时为 nil。
+func (c *Context) Identity() interface{} {
+	return c.identity
+}
+
 // Set 在中间件之间安全地传递数据。
 func (c *Context) Set(key string, value interface{}) {
 	c.storeMutex.Lock()
@@ -76,3 +448,19 @@ func (c *Context) Get(key string) (interface{}, bool) {
 	value, ok := c.store[key]
 	return value, ok
 }
+
+// SetTyped 是 Set 的泛型版本，与 GetTyped 配合使用以避免调用处的类型断言。
+func SetTyped[T any](ctx *Context, key string, value T) {
+	ctx.Set(key, value)
+}
+
+// GetTyped 以类型 T 读取 Set 保存的数据；键不存在或类型不匹配时返回零值和 false。
+func GetTyped[T any](ctx *Context, key string) (T, bool) {
+	value, ok := ctx.Get(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	typed, ok := value.(T)
+	return typed, ok
+}
@@ -0,0 +1,457 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestRequestMeta(t *testing.T) {
+	s := NewServer()
+	s.Handle("meta", func(ctx *Context) {
+		v, _ := ctx.Meta("tenant")
+		ctx.Result(v)
+	})
+	c := startServer(t, s)
+	var r string
+	if err := c.CallWithMeta(map[string]string{"tenant": "t1"}, "meta", nil, &r, 5); err != nil || r != "t1" {
+		t.Fatalf("CallWithMeta = %v, reply %q", err, r)
+	}
+	if err := c.Call("meta", nil, &r, 5); err != nil || r != "" {
+		t.Fatalf("Call without meta = %v, reply %q", err, r)
+	}
+}
+
+func TestContextResetClearsRequestState(t *testing.T) {
+	c := acquireContext()
+	c.Context = context.Background()
+	c.Request = &protocol.Request{Method: "x"}
+	c.Set("k", 1)
+	c.Result(1)
+	c.SetResponseMeta("a", "b")
+	c.AfterResponse(func(error) {})
+	c.rawRequest = []byte("{}")
+	c.streamEnded = true
+	c.reset()
+
+	// 除了保留以便复用的 store 与 afterResponse 底层存储外，所有字段都应回到零值，
+	// 以免上一个请求的状态泄漏到复用该 Context 的下一个请求
+	v := reflect.ValueOf(c).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		switch name {
+		case "store", "storeMutex", "afterResponse":
+			continue
+		case "handlerIdx":
+			if c.handlerIdx != -1 {
+				t.Errorf("handlerIdx = %d after reset", c.handlerIdx)
+			}
+			continue
+		}
+		if !v.Field(i).IsZero() {
+			t.Errorf("field %s was not reset", name)
+		}
+	}
+	if len(c.store) != 0 || len(c.afterResponse) != 0 {
+		t.Errorf("store %v, afterResponse %d after reset", c.store, len(c.afterResponse))
+	}
+	releaseContext(c)
+}
+
+func BenchmarkServerRequest(b *testing.B) {
+	client, server, cleanup := Pipe()
+	defer cleanup()
+	server.Handle("echo", func(ctx *Context) {
+		ctx.Set("seen", true)
+		ctx.Result(ctx.Request.Params)
+	})
+	var reply []int
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := client.Call("echo", []int{1, 2, 3}, &reply, 5); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestContextCopy(t *testing.T) {
+	s := NewServer()
+	res := make(chan interface{}, 1)
+	s.Handle("x", func(ctx *Context) {
+		ctx.Set("k", 1)
+		cp := ctx.Copy()
+		ctx.Set("k", 2)
+		go func() {
+			v, _ := cp.Get("k")
+			// 副本上设置响应无效
+			cp.Result(5)
+			res <- v
+		}()
+		ctx.Result(1)
+	})
+	c := startServer(t, s)
+	var r int
+	if err := c.Call("x", nil, &r, 5); err != nil || r != 1 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+	if v := <-res; v != 1 {
+		t.Fatalf("copy saw k = %v, want the value at Copy time", v)
+	}
+}
+
+func TestContextCopyOutlivesHandler(t *testing.T) {
+	s := NewServer()
+	origs := make(chan context.Context, 1)
+	copies := make(chan *Context, 1)
+	s.Handle("x", func(ctx *Context) {
+		origs <- ctx.Context
+		copies <- ctx.Copy()
+		ctx.Result(1)
+	})
+	c := startServer(t, s)
+	if err := c.Call("x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	// 等到处理器返回后原 context 被取消
+	select {
+	case <-(<-origs).Done():
+	case <-time.After(time.Second):
+		t.Fatal("the original context was not cancelled after the handler returned")
+	}
+	cp := <-copies
+	if err := cp.Err(); err != nil {
+		t.Fatalf("copy.Err() = %v after the handler returned, want nil", err)
+	}
+	select {
+	case <-cp.Done():
+		t.Fatal("copy's Done fired after the handler returned")
+	default:
+	}
+	if _, ok := RequestIDFromContext(cp); !ok {
+		t.Fatal("copy lost the request id value")
+	}
+}
+
+func TestBindStrictRejectsUnknownFields(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) {
+		var p struct {
+			Amount int `json:"amount"`
+		}
+		if err := ctx.BindStrict(&p); err != nil {
+			ctx.Error(err.(*protocol.ErrorObject))
+			return
+		}
+		ctx.Result(p.Amount)
+	})
+	c := startServer(t, s)
+	err := c.Call("x", map[string]int{"amout": 1}, nil, 5)
+	e, ok := AsRPCError(err)
+	if !ok || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("got %v, want InvalidParams", err)
+	}
+	if m, _ := e.Data.(map[string]interface{}); m["field"] != "amout" {
+		t.Fatalf("error data %v does not name the unknown field", e.Data)
+	}
+	var r int
+	if err := c.Call("x", map[string]int{"amount": 3}, &r, 5); err != nil || r != 3 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+}
+
+// codeErr 是实现了 Code 方法的错误
+type codeErr struct{}
+
+func (codeErr) Error() string { return "not enough funds" }
+func (codeErr) Code() int     { return -32050 }
+
+func TestFail(t *testing.T) {
+	s := NewServer()
+	s.Handle("plain", func(ctx *Context) { ctx.Fail(errors.New("boom")) })
+	s.Handle("obj", func(ctx *Context) { ctx.Fail(fmt.Errorf("wrap: %w", protocol.InvalidParamsError("x"))) })
+	s.Handle("coder", func(ctx *Context) { ctx.Fail(codeErr{}) })
+	s.Handle("nil", func(ctx *Context) { ctx.Fail(nil); ctx.Result(1) })
+	c := startServer(t, s)
+
+	e, _ := AsRPCError(c.Call("plain", nil, nil, 5))
+	if e == nil || e.Code != protocol.CodeInternalError || e.Data != "boom" {
+		t.Fatalf("plain: got %+v", e)
+	}
+	e, _ = AsRPCError(c.Call("obj", nil, nil, 5))
+	if e == nil || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("obj: got %+v", e)
+	}
+	e, _ = AsRPCError(c.Call("coder", nil, nil, 5))
+	if e == nil || e.Code != -32050 || e.Message != "not enough funds" {
+		t.Fatalf("coder: got %+v", e)
+	}
+	if err := c.Call("nil", nil, nil, 5); err != nil {
+		t.Fatalf("nil: %v", err)
+	}
+}
+
+func TestAfterResponseReportsWriteErrors(t *testing.T) {
+	s := NewServer()
+	errs := make(chan error, 2)
+	s.Use(func(ctx *Context) {
+		ctx.AfterResponse(func(err error) { errs <- err })
+		ctx.Next()
+	})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	s.Handle("slow", func(ctx *Context) { close(started); <-release; ctx.Result(1) })
+	s.Handle("fast", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+
+	c.Call("fast", nil, nil, 5)
+	if err := <-errs; err != nil {
+		t.Fatalf("fast: write error %v", err)
+	}
+
+	rc := dialRaw(t, s)
+	rc.send(`{"jsonrpc":"2.0","method":"slow","id":1}`)
+	<-started
+	// 客户端在响应写出前重置连接
+	rc.conn.(*net.TCPConn).SetLinger(0)
+	rc.conn.Close()
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	if err := <-errs; err == nil {
+		t.Fatal("expected a write error after the peer reset the connection")
+	}
+}
+
+func TestParamsAndParamsArray(t *testing.T) {
+	s := NewServer()
+	s.Handle("m", func(ctx *Context) { m, err := ctx.Params(); ctx.Fail(err); ctx.Result(m) })
+	s.Handle("a", func(ctx *Context) { a, err := ctx.ParamsArray(); ctx.Fail(err); ctx.Result(a) })
+
+	ctx := s.TestContext("m", map[string]int{"x": 1})
+	if m, _ := ctx.GetResponseResult().(map[string]interface{}); ctx.GetResponseError() != nil || m["x"] != 1.0 {
+		t.Fatalf("Params = %v, %v", ctx.GetResponseResult(), ctx.GetResponseError())
+	}
+	if e := s.TestContext("m", []int{1}).GetResponseError(); e == nil || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("Params on an array: got %+v, want invalid params", e)
+	}
+
+	ctx = s.TestContext("a", []int{1, 2})
+	if a, _ := ctx.GetResponseResult().([]interface{}); len(a) != 2 {
+		t.Fatalf("ParamsArray = %v", ctx.GetResponseResult())
+	}
+	if s.TestContext("a", map[string]int{}).GetResponseError() == nil {
+		t.Fatal("ParamsArray on an object should fail")
+	}
+	// 没有参数时返回 nil
+	ctx = s.TestContext("a", nil)
+	if a, _ := ctx.GetResponseResult().([]interface{}); ctx.GetResponseError() != nil || a != nil {
+		t.Fatalf("ParamsArray without params = %v, %v", ctx.GetResponseResult(), ctx.GetResponseError())
+	}
+}
+
+func TestTypedValues(t *testing.T) {
+	ctx := &Context{}
+	SetTyped(ctx, "n", 3)
+	if v, ok := GetTyped[int](ctx, "n"); !ok || v != 3 {
+		t.Fatalf("GetTyped[int] = %v, %v", v, ok)
+	}
+	if v, ok := GetTyped[string](ctx, "n"); ok || v != "" {
+		t.Fatalf("GetTyped[string] = %q, %v, want the zero value", v, ok)
+	}
+	if _, ok := GetTyped[int](ctx, "x"); ok {
+		t.Fatal("GetTyped reported a missing key as present")
+	}
+}
+
+func TestRequestIDFromContext(t *testing.T) {
+	s := NewServer()
+	s.Handle("id", func(ctx *Context) {
+		v, ok := RequestIDFromContext(ctx)
+		if !ok || v != ctx.RequestID() {
+			ctx.Error(protocol.InternalError("RequestIDFromContext and RequestID disagree"))
+			return
+		}
+		ctx.Result(fmt.Sprintf("%T:%v", v, v))
+	})
+	c := startServer(t, s)
+	var r string
+	if err := c.CallWithID("abc", "id", nil, &r, 5); err != nil || r != "string:abc" {
+		t.Fatalf("string id: %v, %q", err, r)
+	}
+	// 数字 id 以 json.Number 返回，不丢失精度
+	if err := c.CallWithID(uint64(12345), "id", nil, &r, 5); err != nil || r != "json.Number:12345" {
+		t.Fatalf("numeric id: %v, %q", err, r)
+	}
+	if r := s.TestContext("id", nil).GetResponseResult(); r != "json.Number:1" {
+		t.Fatalf("TestContext id: %v", r)
+	}
+}
+
+func TestForward(t *testing.T) {
+	back := NewServer()
+	back.Handle("sum", func(ctx *Context) {
+		var a []int
+		ctx.Bind(&a)
+		v, _ := ctx.Meta("trace")
+		ctx.Result(map[string]interface{}{"sum": a[0] + a[1], "trace": v})
+	})
+	back.Handle("fail", func(ctx *Context) { ctx.Error(protocol.NewError(-32050, "upstream", "x")) })
+	bc := startServer(t, back)
+	front := NewServer()
+	front.HandleDefault(func(ctx *Context) { ctx.Forward(bc) })
+	fc := startServer(t, front)
+
+	var r json.RawMessage
+	if err := fc.CallWithMeta(map[string]string{"trace": "t1"}, "sum", []int{2, 3}, &r, 5); err != nil || string(r) != `{"sum":5,"trace":"t1"}` {
+		t.Fatalf("sum = %v, reply %s", err, r)
+	}
+	// 上游的错误原样写回
+	err := fc.Call("fail", nil, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != -32050 || e.Data != "x" {
+		t.Fatalf("fail: got %v, want the upstream error", err)
+	}
+	err = fc.Call("missing", nil, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeMethodNotFound {
+		t.Fatalf("missing: got %v, want method not found", err)
+	}
+}
+
+func TestBindReportsFieldErrors(t *testing.T) {
+	s := NewServer()
+	s.Handle("bind", func(ctx *Context) {
+		var p struct {
+			A struct {
+				B int `json:"b"`
+			} `json:"a"`
+		}
+		if err := ctx.Bind(&p); err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.Result(p.A.B)
+	})
+	s.Handle("strict", func(ctx *Context) {
+		var p struct {
+			Amount int `json:"amount"`
+		}
+		ctx.Fail(ctx.BindStrict(&p))
+	})
+
+	e := s.TestContext("bind", json.RawMessage(`{"a":{"b":"x"}}`)).GetResponseError()
+	if e == nil || e.Code != protocol.CodeInvalidParams || e.Data != (FieldError{Field: "a.b", Reason: "expected int, got string"}) {
+		t.Fatalf("type mismatch: got %#v", e)
+	}
+	// 语法错误没有字段路径
+	e = s.TestContext("bind", json.RawMessage(`{"a":{"b":}}`)).GetResponseError()
+	if fe, ok := e.Data.(FieldError); !ok || fe.Field != "" || !strings.Contains(fe.Reason, "invalid character") {
+		t.Fatalf("syntax error: got %#v", e)
+	}
+	e = s.TestContext("strict", json.RawMessage(`{"amout":1}`)).GetResponseError()
+	if e == nil || e.Data != (FieldError{Field: "amout", Reason: "unknown field"}) {
+		t.Fatalf("unknown field: got %#v", e)
+	}
+
+	// 经过连接后 data 是 field 与 reason 两个成员的对象
+	c := startServer(t, s)
+	err := c.Call("bind", map[string]interface{}{"a": map[string]interface{}{"b": true}}, nil, 5)
+	re, _ := AsRPCError(err)
+	if m, ok := re.Data.(map[string]interface{}); !ok || m["field"] != "a.b" || m["reason"] != "expected int, got bool" {
+		t.Fatalf("over the wire: got %#v", re)
+	}
+}
+
+func TestMustBind(t *testing.T) {
+	s := NewServer()
+	after := false
+	s.Handle("x", func(ctx *Context) {
+		var p struct{ N int }
+		if !ctx.MustBind(&p) {
+			return
+		}
+		ctx.Result(p.N)
+		ctx.Next()
+	}, func(ctx *Context) { after = true })
+
+	ctx := s.TestContext("x", json.RawMessage(`{"N":3}`))
+	if ctx.GetResponseResult() != 3 || !after || ctx.IsAborted() {
+		t.Fatalf("valid params: result %v, after %v, aborted %v", ctx.GetResponseResult(), after, ctx.IsAborted())
+	}
+	after = false
+	ctx = s.TestContext("x", json.RawMessage(`{"N":"a"}`))
+	if e := ctx.GetResponseError(); e == nil || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("invalid params: got %+v, want invalid params", e)
+	}
+	if !ctx.IsAborted() || after {
+		t.Fatal("MustBind should abort the handler chain on failure")
+	}
+}
+
+func TestBindScalar(t *testing.T) {
+	s := NewServer()
+	s.Handle("int", func(ctx *Context) {
+		var n int
+		if err := ctx.BindScalar(&n); err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.Result(n + 1)
+	})
+	s.Handle("str", func(ctx *Context) {
+		var v string
+		ctx.Fail(ctx.BindScalar(&v))
+		ctx.Result(v)
+	})
+	s.Handle("bool", func(ctx *Context) {
+		var v bool
+		ctx.Fail(ctx.BindScalar(&v))
+		ctx.Result(!v)
+	})
+
+	if r := s.TestContext("int", json.RawMessage(`5`)).GetResponseResult(); r != 6 {
+		t.Fatalf("int = %v", r)
+	}
+	if r := s.TestContext("str", json.RawMessage(`"text"`)).GetResponseResult(); r != "text" {
+		t.Fatalf("str = %v", r)
+	}
+	if r := s.TestContext("bool", json.RawMessage(`true`)).GetResponseResult(); r != false {
+		t.Fatalf("bool = %v", r)
+	}
+	for _, p := range []string{`[5]`, `{"a":1}`, `null`, `"x"`} {
+		if e := s.TestContext("int", json.RawMessage(p)).GetResponseError(); e == nil || e.Code != protocol.CodeInvalidParams {
+			t.Fatalf("params %s: got %+v, want invalid params", p, e)
+		}
+	}
+
+	c := startServer(t, s)
+	var r int
+	if err := c.Call("int", 41, &r, 5); err != nil || r != 42 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+}
+
+func TestRawRequest(t *testing.T) {
+	s := NewServer()
+	got := make(chan []byte, 2)
+	s.Handle("audit", func(ctx *Context) { got <- append([]byte(nil), ctx.RawRequest()...); ctx.Result(1) })
+	c := startServer(t, s)
+
+	// 原样保留请求的字节，包括空白与成员顺序
+	rc := dialRaw(t, s)
+	msg := `{"jsonrpc":"2.0",  "method":"audit","params":{"b":2,"a":1},"id":7}`
+	rc.send(msg)
+	if r := <-got; string(r) != msg {
+		t.Fatalf("RawRequest = %s, want %s", r, msg)
+	}
+	c.Call("audit", []int{1}, nil, 5)
+	if r := <-got; !strings.Contains(string(r), `"params":[1]`) {
+		t.Fatalf("RawRequest = %s", r)
+	}
+}
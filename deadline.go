@@ -0,0 +1,63 @@
+package jsonrpc2
+
+import (
+	"context"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// DeadlineMetaKey 是请求 meta 中携带调用期限的 key，值为 RFC 3339 格式的绝对时间。
+const DeadlineMetaKey = "deadline"
+
+// WithDeadlinePropagation 使客户端在通过 CallContext（以及 Call、CallWithMeta）发起调用时，
+// 将 ctx 的期限写入请求 meta 的 deadline 成员，配合服务端的 WithDeadlineFromMeta 使服务端在客户端放弃后及时停止处理。
+func WithDeadlinePropagation() DialOption {
+	return dialOptionFunc(func(c *Client) {
+		c.propagateDeadline = true
+	})
+}
+
+// WithDeadlineFromMeta 使服务端读取请求 meta 中的 deadline，并以此为处理器的 Context 设置期限。
+// tolerance 用于容忍客户端与服务端之间的时钟偏差：实际期限为客户端期限加上 tolerance。
+// 请求到达时期限已过的请求不会进入处理链，直接返回 DeadlineExceeded 错误；无法解析的 deadline 会被忽略。
+func WithDeadlineFromMeta(tolerance time.Duration) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.deadlineFromMeta = true
+		s.deadlineTolerance = tolerance
+	})
+}
+
+// withDeadlineMeta 返回加入了期限的 meta 副本，不修改调用方传入的 map；
+// 调用方已在 meta 中显式设置了 deadline 时保持不变
+func withDeadlineMeta(meta map[string]string, deadline time.Time) map[string]string {
+	if _, ok := meta[DeadlineMetaKey]; ok {
+		return meta
+	}
+	cp := make(map[string]string, len(meta)+1)
+	for k, v := range meta {
+		cp[k] = v
+	}
+	cp[DeadlineMetaKey] = deadline.UTC().Format(time.RFC3339Nano)
+	return cp
+}
+
+// applyDeadline 根据请求 meta 中的期限派生处理器的 context。
+// 期限已过时返回 DeadlineExceeded 错误，调用方应直接返回该错误而不执行处理链。
+func (s *Server) applyDeadline(base context.Context, req *protocol.Request) (context.Context, context.CancelFunc, *protocol.ErrorObject) {
+	value, ok := req.Meta[DeadlineMetaKey]
+	if !s.deadlineFromMeta || !ok {
+		return base, func() {}, nil
+	}
+	deadline, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		s.log().Debugf("jsonrpc2: ignoring invalid deadline %q: %v", value, err)
+		return base, func() {}, nil
+	}
+	deadline = deadline.Add(s.deadlineTolerance)
+	if !time.Now().Before(deadline) {
+		return base, func() {}, protocol.DeadlineExceededError(value)
+	}
+	ctx, cancel := context.WithDeadline(base, deadline)
+	return ctx, cancel, nil
+}
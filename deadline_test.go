@@ -0,0 +1,46 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestDeadlinePropagation(t *testing.T) {
+	s := NewServer(WithDeadlineFromMeta(10 * time.Millisecond))
+	expired := make(chan error, 1)
+	s.Handle("slow", func(ctx *Context) {
+		<-ctx.Done()
+		expired <- ctx.Err()
+		ctx.Fail(ctx.Err())
+	})
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	startServer(t, s)
+	c, err := Dial(s.listener.Addr().String(), WithDeadlinePropagation())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := c.CallContext(ctx, "slow", nil, nil); err != ErrTimeout {
+		t.Fatalf("got %v, want ErrTimeout", err)
+	}
+	if err := <-expired; err != context.DeadlineExceeded {
+		t.Fatalf("handler ctx.Err() = %v, want context.DeadlineExceeded", err)
+	}
+
+	// 已经过期的期限在分发前直接返回错误
+	past := map[string]string{DeadlineMetaKey: time.Now().Add(-time.Second).UTC().Format(time.RFC3339Nano)}
+	err = c.CallWithMeta(past, "x", nil, nil, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeDeadlineExceeded {
+		t.Fatalf("got %v, want a deadline exceeded error", err)
+	}
+	// 无法解析的期限被忽略
+	if err := c.CallWithMeta(map[string]string{DeadlineMetaKey: "junk"}, "x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+}
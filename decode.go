@@ -0,0 +1,198 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+// ErrMessageTooLarge 表示单条消息超过了 WithMaxMessageBytes 设定的上限。
+var ErrMessageTooLarge = errors.New("jsonrpc2: message too large")
+
+// errResyncUnsupported 表示当前编解码器无法在解析失败后跳过损坏的消息
+var errResyncUnsupported = errors.New("jsonrpc2: codec does not support resynchronization")
+
+// streamDecoder 从连接中逐条解码消息。
+// 未设置 Codec 时使用 json.Decoder，消息之间可以用换行或空白分隔，也可以首尾相连（如 {...}{...}），
+// 类型不匹配的消息会被整条跳过；语法错误后则跳到下一行继续解析，因此紧密相连的消息中出现语法错误时，
+// 同一行中其后的消息会被一并丢弃。
+// 设置了 Codec 时每条消息交由 Codec.Decode 从带缓冲的读取器中读取。
+// 两种模式下都可以通过 max 限制单条消息的字节数；实现了 messageLimiter 的 Codec 还会用它限制解码后的大小。
+type streamDecoder struct {
+	max     int64
+	codec   Codec
+	reader  *resyncReader
+	limiter *limitReader
+	json    *json.Decoder
+	buf     *bufio.Reader // 仅在 Codec 模式下使用
+}
+
+func newStreamDecoder(r io.Reader, max int64, codec Codec) *streamDecoder {
+	if l, ok := codec.(messageLimiter); ok && max > 0 {
+		codec = l.withMaxMessageBytes(max)
+	}
+	d := &streamDecoder{
+		max:    max,
+		codec:  codec,
+		reader: &resyncReader{r: r},
+	}
+	d.reset()
+	return d
+}
+
+// reset 基于 reader 重新构建底层解码器
+func (d *streamDecoder) reset() {
+	var r io.Reader = d.reader
+	d.limiter = nil
+	if d.max > 0 {
+		d.limiter = &limitReader{r: r, max: d.max}
+		r = d.limiter
+	}
+	if d.codec != nil {
+		d.buf = bufio.NewReader(r)
+		return
+	}
+	d.json = json.NewDecoder(r)
+}
+
+// Decode 解码下一条消息到 v 中
+func (d *streamDecoder) Decode(v interface{}) error {
+	if d.codec != nil {
+		if d.limiter != nil {
+			d.limiter.begin(d.limiter.read - int64(d.buf.Buffered()))
+		}
+		return d.codec.Decode(d.buf, v)
+	}
+	if d.limiter != nil {
+		d.limiter.begin(d.json.InputOffset())
+	}
+	return d.json.Decode(v)
+}
+
+// resync 丢弃当前损坏的一行数据，使后续消息可以继续被解析
+func (d *streamDecoder) resync() error {
+	if d.codec != nil {
+		return errResyncUnsupported
+	}
+	if err := d.reader.skipLine(d.json.Buffered()); err != nil {
+		return err
+	}
+	d.reset()
+	return nil
+}
+
+// recoverID 尝试从解析失败的消息中取出 id 成员，使解析错误的响应仍能与请求对应。
+// 只检查解码器已读入缓冲区的数据，不会阻塞读取；Codec 模式或无法取得合法 id 时返回 nil。
+func (d *streamDecoder) recoverID() interface{} {
+	if d.codec != nil {
+		return nil
+	}
+	data, err := io.ReadAll(d.json.Buffered())
+	if err != nil {
+		return nil
+	}
+	if id := extractID(data); id != nil {
+		return id
+	}
+	return nil
+}
+
+// extractID 宽松地逐个成员扫描 JSON 对象，在遇到语法错误之前找到 id 成员时返回它的原始值。
+// 位于损坏成员之后的 id 无法取得。
+func extractID(data []byte) json.RawMessage {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil
+		}
+		key, ok := tok.(string)
+		if !ok {
+			return nil
+		}
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil
+		}
+		if key == "id" {
+			if len(value) == 0 || !validID(value) {
+				return nil
+			}
+			return value
+		}
+	}
+	return nil
+}
+
+// limitReader 限制解码单条消息所能读取的字节数。
+// 每次解码前调用 begin 记录消息起始位置，此后从该位置起读取超过 max 字节即返回 ErrMessageTooLarge。
+type limitReader struct {
+	r    io.Reader
+	max  int64
+	read int64 // 从 r 读取的总字节数
+	base int64 // 当前消息的起始位置
+}
+
+// begin 将下一条消息的起始位置设为解码器当前已消费的偏移量
+func (l *limitReader) begin(offset int64) {
+	l.base = offset
+}
+
+func (l *limitReader) Read(p []byte) (int, error) {
+	remaining := l.max - (l.read - l.base)
+	if remaining <= 0 {
+		return 0, ErrMessageTooLarge
+	}
+	if int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	return n, err
+}
+
+// resyncReader 是连接之上的一层读取器，支持在解析失败后丢弃数据直到下一个换行符，
+// 从而跳过损坏的消息，继续解析后续消息。
+type resyncReader struct {
+	r       io.Reader
+	pending []byte // 先于 r 读出的数据
+}
+
+func (rr *resyncReader) Read(p []byte) (int, error) {
+	if len(rr.pending) > 0 {
+		n := copy(p, rr.pending)
+		rr.pending = rr.pending[n:]
+		return n, nil
+	}
+	return rr.r.Read(p)
+}
+
+// skipLine 将解码器中尚未消费的数据放回读取器，然后丢弃直到并包括下一个换行符的数据。
+func (rr *resyncReader) skipLine(buffered io.Reader) error {
+	rest, err := io.ReadAll(buffered)
+	if err != nil {
+		return err
+	}
+	rr.pending = append(rest, rr.pending...)
+
+	buf := make([]byte, 512)
+	for {
+		if len(rr.pending) == 0 {
+			n, err := rr.r.Read(buf)
+			if n == 0 && err != nil {
+				return err
+			}
+			rr.pending = append(rr.pending, buf[:n]...)
+		}
+		if i := bytes.IndexByte(rr.pending, '\n'); i >= 0 {
+			rr.pending = rr.pending[i+1:]
+			return nil
+		}
+		rr.pending = rr.pending[:0]
+	}
+}
@@ -0,0 +1,102 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestMaxMessageBytes(t *testing.T) {
+	s := NewServer(WithMaxMessageBytes(200))
+	s.Handle("x", func(ctx *Context) { ctx.Result(strings.Repeat("a", 300)) })
+	startServer(t, s)
+	dial := func(opts ...DialOption) *Client {
+		c, err := Dial(s.listener.Addr().String(), opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+		return c
+	}
+
+	// 超过服务端上限的请求被拒绝
+	if err := dial().Call("x", strings.Repeat("a", 300), nil, 5); err == nil {
+		t.Fatal("oversized request succeeded")
+	}
+	// 超过客户端上限的响应使调用失败
+	if err := dial(WithMaxMessageBytes(100)).Call("x", nil, nil, 5); err == nil {
+		t.Fatal("oversized response succeeded")
+	}
+	c := dial(WithMaxMessageBytes(1000))
+	for i := 0; i < 3; i++ {
+		if err := c.Call("x", nil, nil, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestServerResyncsAfterMalformedLines(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(7) })
+	startServer(t, s)
+	rc := dialRaw(t, s)
+	rc.send("{bad json}\nnot json either\n{\"jsonrpc\":\"2.0\",\"method\":\"x\",\"id\":1}\n")
+	for i := 0; i < 2; i++ {
+		if resp := rc.read(); errorCode(resp) != protocol.CodeParseError {
+			t.Fatalf("response %d: %v, want a parse error", i, resp)
+		}
+	}
+	// 跳过损坏的行之后仍能处理后续的请求
+	if resp := rc.read(); resp["result"] != float64(7) || resp["id"] != float64(1) {
+		t.Fatalf("got %v", resp)
+	}
+}
+
+func TestParseErrorEchoesRecoverableID(t *testing.T) {
+	s := NewServer()
+	a, b := net.Pipe()
+	go s.ServeConn(a)
+	defer b.Close()
+	r := bufio.NewReader(b)
+	for _, tc := range []struct{ in, want string }{
+		{`{"jsonrpc":"2.0","id":7,"method":"x","params":{bad}}`, `"id":7`},
+		{`{"jsonrpc":"2.0","id":"abc","params":[1,}`, `"id":"abc"`},
+		// id 出现在损坏的位置之后时无法确定
+		{`{"jsonrpc":"2.0","params":{bad},"id":9}`, `"id":null`},
+		{`{"jsonrpc":"2.0","id":{},"params":x}`, `"id":null`},
+	} {
+		go b.Write([]byte(tc.in + "\n"))
+		line, err := r.ReadString('\n')
+		if err != nil || !strings.Contains(line, tc.want) || !strings.Contains(line, "-32700") {
+			t.Fatalf("%s: got %q, %v, want a parse error with %s", tc.in, line, err, tc.want)
+		}
+	}
+}
+
+func TestServerDecodesConcatenatedMessages(t *testing.T) {
+	s := NewServer()
+	s.Handle("echo", func(ctx *Context) { ctx.Result(string(ctx.Request.Params)) })
+	a, b := net.Pipe()
+	go s.ServeConn(a)
+	defer b.Close()
+	// 消息之间没有换行，其中夹杂着无效的请求
+	go b.Write([]byte(`{"jsonrpc":"2.0","id":1,"method":"echo","params":[1]}{"jsonrpc":"2.0","id":2,"method":5}{"jsonrpc":"2.0","id":3,"method":"echo","params":[3]}"str"{"jsonrpc":"2.0","id":4,"method":"echo","params":[4]}`))
+	r := bufio.NewReader(b)
+	var lines []string
+	for i := 0; i < 5; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	all := strings.Join(lines, "")
+	for _, want := range []string{`"result":"[1]","id":1`, `"id":2}`, `"result":"[3]","id":3`, `protocol.Request"},"id":null`, `"result":"[4]","id":4`} {
+		if !strings.Contains(all, want) {
+			t.Fatalf("missing %s in responses:\n%s", want, all)
+		}
+	}
+}
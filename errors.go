@@ -0,0 +1,77 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// ErrTimeout 表示调用在超时时间内未收到响应。
+var ErrTimeout = errors.New("jsonrpc2: call timeout")
+
+// ErrTooManyPending 表示等待响应的调用数量已达到 WithMaxPending 设定的上限。
+var ErrTooManyPending = errors.New("jsonrpc2: too many pending calls")
+
+// ErrCancelled 表示调用已通过 Client.Cancel 取消。
+var ErrCancelled = errors.New("jsonrpc2: call cancelled")
+
+// ErrShutdown 表示客户端已关闭或连接已断开，无法再发出新的调用或通知。
+var ErrShutdown = errors.New("jsonrpc2: client is shut down or closing")
+
+// ErrClientClosed 表示调用因客户端被 Close 关闭而结束。
+var ErrClientClosed = errors.New("jsonrpc2: client closed")
+
+// ErrDiscoveryUnsupported 表示服务端没有提供 rpc.discover 方法，由 Client.Discover 返回。
+var ErrDiscoveryUnsupported = errors.New("jsonrpc2: server does not support rpc.discover")
+
+// AsRPCError 从调用返回的 error 中提取服务端返回的结构化错误对象。
+// 当 err（或其包装链中的某个错误）是 *protocol.ErrorObject 时返回该对象与 true，
+// 调用方可借此读取 Code 与 Data；连接错误、超时等客户端错误返回 nil 与 false。
+func AsRPCError(err error) (*protocol.ErrorObject, bool) {
+	var rpcErr *protocol.ErrorObject
+	if errors.As(err, &rpcErr) {
+		return rpcErr, true
+	}
+	return nil, false
+}
+
+// RetryAfter 读取服务端错误（如 protocol.UnavailableError）在 data 的 retry_after_ms 成员中携带的重试等待时间。
+// err 不是服务端返回的错误或没有携带重试提示时返回 0 与 false。
+func RetryAfter(err error) (time.Duration, bool) {
+	rpcErr, ok := AsRPCError(err)
+	if !ok || rpcErr.Data == nil {
+		return 0, false
+	}
+	// 客户端收到的 data 是解码后的 map，服务端构造的则是原始类型，统一经 JSON 读取
+	raw, marshalErr := json.Marshal(rpcErr.Data)
+	if marshalErr != nil {
+		return 0, false
+	}
+	var hint struct {
+		RetryAfterMs *float64 `json:"retry_after_ms"`
+	}
+	if json.Unmarshal(raw, &hint) != nil || hint.RetryAfterMs == nil || *hint.RetryAfterMs < 0 {
+		return 0, false
+	}
+	return time.Duration(*hint.RetryAfterMs * float64(time.Millisecond)), true
+}
+
+// Coder 可由业务错误实现，以便 ctx.Fail 将其映射为指定的 JSON-RPC 错误码。
+type Coder interface {
+	Code() int
+}
+
+// toErrorObject 将 Go error 转换为响应中的错误对象：*protocol.ErrorObject 原样使用，
+// 实现了 Coder 的错误使用其错误码与错误信息，其余错误包装为以错误信息为 data 的 InternalError。
+func toErrorObject(err error) *protocol.ErrorObject {
+	if rpcErr, ok := AsRPCError(err); ok {
+		return rpcErr
+	}
+	var coder Coder
+	if errors.As(err, &coder) {
+		return protocol.NewError(coder.Code(), err.Error(), nil)
+	}
+	return protocol.InternalError(err.Error())
+}
@@ -0,0 +1,56 @@
+package jsonrpc2
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestAsRPCError(t *testing.T) {
+	s := NewServer()
+	s.Handle("fail", func(ctx *Context) { ctx.Error(protocol.NewError(-32001, "Unauthorized", "tok")) })
+	s.Handle("ok", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+
+	err := c.Call("fail", nil, nil, 5)
+	e, ok := AsRPCError(err)
+	if !ok {
+		t.Fatalf("AsRPCError(%v) = false", err)
+	}
+	if e.Code != -32001 || e.Message != "Unauthorized" || e.Data != "tok" {
+		t.Fatalf("got %+v", e)
+	}
+	var r int
+	if err := c.Call("ok", nil, &r, 5); err != nil || r != 1 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+	if _, ok := AsRPCError(ErrTimeout); ok {
+		t.Fatal("AsRPCError(ErrTimeout) = true")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	s := NewServer()
+	s.Handle("limited", func(ctx *Context) { ctx.Error(protocol.UnavailableError(1500 * time.Millisecond)) })
+	s.Handle("other", func(ctx *Context) { ctx.Error(protocol.InternalError("x")) })
+	c := startServer(t, s)
+
+	err := c.Call("limited", nil, nil, 5)
+	if d, ok := RetryAfter(err); !ok || d != 1500*time.Millisecond {
+		t.Fatalf("RetryAfter(%v) = %v, %v, want 1.5s", err, d, ok)
+	}
+	if e, _ := AsRPCError(err); e == nil || e.Code != protocol.CodeUnavailable {
+		t.Fatalf("got %+v, want an unavailable error", e)
+	}
+	// 未经过连接的错误同样可以读取
+	if d, ok := RetryAfter(protocol.UnavailableError(time.Second)); !ok || d != time.Second {
+		t.Fatalf("RetryAfter(local error) = %v, %v", d, ok)
+	}
+	if _, ok := RetryAfter(c.Call("other", nil, nil, 5)); ok {
+		t.Fatal("RetryAfter reported a hint for an internal error")
+	}
+	if _, ok := RetryAfter(ErrTimeout); ok {
+		t.Fatal("RetryAfter reported a hint for ErrTimeout")
+	}
+}
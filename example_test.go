@@ -0,0 +1,54 @@
+package jsonrpc2_test
+
+import (
+	"fmt"
+
+	"github.com/kyle-cao/jsonrpc2"
+)
+
+func ExamplePipe() {
+	client, server, cleanup := jsonrpc2.Pipe()
+	defer cleanup()
+	server.Handle("Arith.Add", func(ctx *jsonrpc2.Context) {
+		var p []int
+		if !ctx.MustBind(&p) {
+			return
+		}
+		ctx.Result(p[0] + p[1])
+	})
+
+	var reply int
+	if err := client.Call("Arith.Add", []int{1, 2}, &reply, 5); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(reply)
+	// Output: 3
+}
+
+func ExampleNewTestServer() {
+	ts := jsonrpc2.NewTestServer()
+	defer ts.Close()
+	ts.Handle("greet", func(ctx *jsonrpc2.Context) {
+		var name string
+		if !ctx.MustBind(&name) {
+			return
+		}
+		ctx.Result("hello, " + name)
+	})
+
+	// 经过客户端与连接调用
+	var reply string
+	if err := ts.Client.Call("greet", "gopher", &reply, 5); err != nil {
+		fmt.Println(err)
+		return
+	}
+	fmt.Println(reply)
+
+	// 不经过网络直接执行处理链
+	ctx := ts.TestContext("greet", "pipe")
+	fmt.Println(ctx.GetResponseResult())
+	// Output:
+	// hello, gopher
+	// hello, pipe
+}
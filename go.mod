@@ -2,4 +2,7 @@ module github.com/kyle-cao/jsonrpc2
 
 go 1.25.3
 
-require github.com/google/uuid v1.6.0
+require (
+	github.com/google/uuid v1.6.0
+	golang.org/x/time v0.14.0
+)
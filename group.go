@@ -0,0 +1,58 @@
+package jsonrpc2
+
+// Group 代表一组共享方法名前缀与中间件的路由。
+// 组内注册的方法名为 "前缀.方法名"，处理链顺序为：全局中间件 → 组中间件 → 路由处理器。
+type Group struct {
+	server      *Server
+	prefix      string
+	middlewares []HandlerFunc
+}
+
+// Group 创建一个以 prefix 为前缀的路由组，middlewares 将应用于组内所有方法。
+func (s *Server) Group(prefix string, middlewares ...HandlerFunc) *Group {
+	return &Group{
+		server:      s,
+		prefix:      prefix,
+		middlewares: middlewares,
+	}
+}
+
+// Group 基于当前组创建嵌套路由组，前缀与中间件会逐层叠加。
+func (g *Group) Group(prefix string, middlewares ...HandlerFunc) *Group {
+	return &Group{
+		server:      g.server,
+		prefix:      g.joinName(prefix),
+		middlewares: g.combine(middlewares),
+	}
+}
+
+// Use 向路由组追加中间件，只影响之后注册的方法。
+func (g *Group) Use(middlewares ...HandlerFunc) {
+	g.middlewares = append(g.middlewares, middlewares...)
+}
+
+// Handle 在组内注册方法，组中间件会被放在 handlers 之前。
+func (g *Group) Handle(method string, handlers ...HandlerFunc) {
+	if len(handlers) == 0 {
+		panic("jsonrpc2: handler chain cannot be empty")
+	}
+	g.server.Handle(g.joinName(method), g.combine(handlers)...)
+}
+
+// joinName 用 "." 连接组前缀与方法名
+func (g *Group) joinName(name string) string {
+	if g.prefix == "" {
+		return name
+	}
+	if name == "" {
+		return g.prefix
+	}
+	return g.prefix + "." + name
+}
+
+// combine 返回组中间件与 handlers 拼接后的新切片，避免共享底层数组
+func (g *Group) combine(handlers []HandlerFunc) []HandlerFunc {
+	chain := make([]HandlerFunc, 0, len(g.middlewares)+len(handlers))
+	chain = append(chain, g.middlewares...)
+	return append(chain, handlers...)
+}
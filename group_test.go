@@ -0,0 +1,30 @@
+package jsonrpc2
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGroupMiddleware(t *testing.T) {
+	s := NewServer()
+	var order []string
+	mark := func(name string) HandlerFunc {
+		return func(ctx *Context) {
+			order = append(order, name)
+			ctx.Next()
+		}
+	}
+	s.Use(mark("global"))
+	g := s.Group("user", mark("group"))
+	g.Group("admin", mark("sub")).Handle("create", func(ctx *Context) {
+		order = append(order, "handler")
+		ctx.Result(1)
+	})
+	c := startServer(t, s)
+	if err := c.Call("user.admin.create", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ","); got != "global,group,sub,handler" {
+		t.Fatalf("middleware order %s", got)
+	}
+}
@@ -1,4 +1,32 @@
 package jsonrpc2
 
+import "context"
+
 // HandlerFunc 是处理 RPC 请求的最终函数类型。
 type HandlerFunc func(ctx *Context)
+
+// H 将返回 (result, error) 的类型化函数适配为 HandlerFunc，省去手动调用 Bind、Result 与 Error：
+//
+//	server.Handle("Arith.Add", jsonrpc2.H(func(ctx context.Context, p AddParams) (int, error) {
+//		return p.A + p.B, nil
+//	}))
+//
+// params 按 ctx.Bind 的规则解析到 In 中，解析失败时返回 InvalidParamsError 且不调用 fn；请求没有 params 时 In 为零值。
+// fn 收到的 ctx 即请求的 *Context，可以传给下游或用于感知取消。fn 返回的错误按 ctx.Fail 的规则设置：
+// *protocol.ErrorObject 原样返回，实现了 Coder 的错误使用其错误码，其余错误包装为 InternalError。
+func H[In, Out any](fn func(ctx context.Context, in In) (Out, error)) HandlerFunc {
+	return func(ctx *Context) {
+		var in In
+		if params := ctx.Request.Params; len(params) > 0 && string(params) != "null" {
+			if !ctx.MustBind(&in) {
+				return
+			}
+		}
+		out, err := fn(ctx, in)
+		if err != nil {
+			ctx.Fail(err)
+			return
+		}
+		ctx.Result(out)
+	}
+}
@@ -0,0 +1,37 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestH(t *testing.T) {
+	type add struct{ A, B int }
+	s := NewServer()
+	s.Handle("add", H(func(ctx context.Context, p add) (int, error) { return p.A + p.B, nil }))
+	s.Handle("rpcerr", H(func(ctx context.Context, p []int) (int, error) {
+		return 0, protocol.NewError(-32010, "custom", "d")
+	}))
+	s.Handle("plain", H(func(ctx context.Context, _ struct{}) (string, error) { return "", errors.New("boom") }))
+	c := startServer(t, s)
+
+	var r int
+	if err := c.Call("add", add{2, 3}, &r, 5); err != nil || r != 5 {
+		t.Fatalf("add = %v, reply %d", err, r)
+	}
+	err := c.Call("add", map[string]string{"A": "x"}, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("bad params: got %v, want invalid params", err)
+	}
+	err = c.Call("rpcerr", []int{1}, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != -32010 || e.Message != "custom" {
+		t.Fatalf("rpcerr: got %v, want the handler's error", err)
+	}
+	err = c.Call("plain", nil, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeInternalError || e.Data != "boom" {
+		t.Fatalf("plain: got %v, want an internal error", err)
+	}
+}
@@ -0,0 +1,71 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+)
+
+// startServer 在本地随机端口上启动 s，返回一个连接到它的客户端，测试结束时关闭二者
+func startServer(t *testing.T, s *Server) *Client {
+	t.Helper()
+	if err := s.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close(context.Background()) })
+	c, err := Dial(s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// rawConn 是一个直接收发原始报文的测试连接，用于构造客户端无法发出的畸形请求
+type rawConn struct {
+	t    *testing.T
+	conn net.Conn
+	dec  *json.Decoder
+}
+
+// dialRaw 建立到 s 的原始 TCP 连接，测试结束时关闭
+func dialRaw(t *testing.T, s *Server) *rawConn {
+	t.Helper()
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &rawConn{t: t, conn: conn, dec: json.NewDecoder(conn)}
+}
+
+// send 原样写出 data
+func (rc *rawConn) send(data string) {
+	rc.t.Helper()
+	if _, err := rc.conn.Write([]byte(data)); err != nil {
+		rc.t.Fatal(err)
+	}
+}
+
+// read 在一秒内读取下一条响应
+func (rc *rawConn) read() map[string]interface{} {
+	rc.t.Helper()
+	rc.conn.SetReadDeadline(time.Now().Add(time.Second))
+	var resp map[string]interface{}
+	if err := rc.dec.Decode(&resp); err != nil {
+		rc.t.Fatal(err)
+	}
+	return resp
+}
+
+// errorCode 返回响应中错误的 code，没有错误时返回 0
+func errorCode(resp map[string]interface{}) int {
+	e, ok := resp["error"].(map[string]interface{})
+	if !ok {
+		return 0
+	}
+	code, _ := e["code"].(float64)
+	return int(code)
+}
@@ -0,0 +1,126 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+)
+
+// Invoker 代表一次同步调用。拦截器通过包装 Invoker 来观察、修改或中断调用。
+type Invoker func(ctx context.Context, method string, args, reply interface{}) error
+
+// Interceptor 是客户端的调用拦截器（中间件）。
+// 拦截器可以在调用 next 之前或之后执行代码，也可以不调用 next 直接返回错误以中断调用。
+type Interceptor func(next Invoker) Invoker
+
+type metaContextKey struct{}
+
+// ContextWithMeta 返回一个携带请求元数据的 context，通过 CallContext 发起调用时
+// 这些元数据会被放入请求的 meta 成员中。拦截器可借此注入鉴权令牌、关联 ID 等信息。
+func ContextWithMeta(ctx context.Context, meta map[string]string) context.Context {
+	return context.WithValue(ctx, metaContextKey{}, meta)
+}
+
+// MetaFromContext 读取通过 ContextWithMeta 设置的请求元数据。
+func MetaFromContext(ctx context.Context) map[string]string {
+	meta, _ := ctx.Value(metaContextKey{}).(map[string]string)
+	return meta
+}
+
+// CallMeta 接收一次调用在传输层之上返回的元数据。
+type CallMeta struct {
+	// Meta 是响应 meta 成员中服务端通过 ctx.SetResponseMeta 设置的元数据
+	Meta map[string]string
+}
+
+type callMetaContextKey struct{}
+
+// ContextWithCallMeta 返回一个携带 *CallMeta 的 context，通过 CallContext 发起的调用
+// 收到响应后会将响应元数据填入 cm。不需要响应元数据的调用无需设置。
+func ContextWithCallMeta(ctx context.Context, cm *CallMeta) context.Context {
+	return context.WithValue(ctx, callMetaContextKey{}, cm)
+}
+
+// Use 为客户端追加一个或多个拦截器。
+// 拦截器作用于 Call、CallWithMeta 与 CallContext，先添加的拦截器位于最外层。
+func (c *Client) Use(interceptors ...Interceptor) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.interceptors = append(c.interceptors, interceptors...)
+}
+
+// CallContext 发起一个同步调用，使用内部自增 ID，调用受 ctx 的取消与期限控制。
+func (c *Client) CallContext(ctx context.Context, method string, args, reply interface{}) error {
+	c.mutex.Lock()
+	interceptors := c.interceptors
+	c.mutex.Unlock()
+
+	invoker := c.invoke
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		invoker = interceptors[i](invoker)
+	}
+	return invoker(ctx, method, args, reply)
+}
+
+// invoke 是拦截器链末端的 Invoker，负责真正发送请求并等待响应。
+func (c *Client) invoke(ctx context.Context, method string, args, reply interface{}) error {
+	id := c.nextID()
+	meta := MetaFromContext(ctx)
+	if deadline, ok := ctx.Deadline(); ok && c.propagateDeadline {
+		meta = withDeadlineMeta(meta, deadline)
+	}
+	call := &Call{
+		Method: method,
+		Args:   args,
+		Reply:  reply,
+		Meta:   meta,
+		Done:   make(chan *Call, 1),
+	}
+	c.sendContext(ctx, id, call)
+
+	select {
+	case <-call.Done:
+		fillCallMeta(ctx, call)
+		return call.Error
+	case <-ctx.Done():
+		if !c.forget(id, call) {
+			// 响应已被接收循环取走，等待其写完 Reply，避免返回后仍被并发写入
+			<-call.Done
+			fillCallMeta(ctx, call)
+			return call.Error
+		}
+		return contextError(ctx.Err())
+	}
+}
+
+// contextError 将 ctx 结束的原因转换为调用的错误：期限已过为 ErrTimeout，其他原因原样返回
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrTimeout
+	}
+	return err
+}
+
+// fillCallMeta 将已完成调用的响应元数据填入 ctx 中的 *CallMeta
+func fillCallMeta(ctx context.Context, call *Call) {
+	if cm, ok := ctx.Value(callMetaContextKey{}).(*CallMeta); ok && cm != nil {
+		cm.Meta = call.ResponseMeta
+	}
+}
+
+// forget 将调用从 pending 中移除，之后到达的响应会被丢弃。
+// 返回 false 表示该调用已不在 pending 中（已完成或正在完成）。
+func (c *Client) forget(id interface{}, call *Call) bool {
+	idKey, err := idToKey(id)
+	if err != nil {
+		return false
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.pending[idKey] != call {
+		return false
+	}
+	delete(c.pending, idKey)
+	delete(c.progressHandlers, idKey)
+	c.pendingCond.Broadcast()
+	return true
+}
@@ -0,0 +1,66 @@
+package jsonrpc2
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestClientInterceptors(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) {
+		v, _ := ctx.Meta("tok")
+		ctx.Result(v)
+	})
+	c := startServer(t, s)
+	var seen []string
+	c.Use(func(next Invoker) Invoker {
+		return func(ctx context.Context, method string, args, reply interface{}) error {
+			seen = append(seen, "outer:"+method)
+			return next(ctx, method, args, reply)
+		}
+	}, func(next Invoker) Invoker {
+		return func(ctx context.Context, method string, args, reply interface{}) error {
+			seen = append(seen, "inner:"+method)
+			if method == "blocked" {
+				return protocol.NewError(1, "blocked", nil)
+			}
+			return next(ContextWithMeta(ctx, map[string]string{"tok": "abc"}), method, args, reply)
+		}
+	})
+	var r string
+	if err := c.Call("x", nil, &r, 5); err != nil || r != "abc" {
+		t.Fatalf("Call = %v, reply %q", err, r)
+	}
+	if err := c.Call("blocked", nil, &r, 5); err == nil {
+		t.Fatal("interceptor did not short-circuit the call")
+	}
+	want := "outer:x,inner:x,outer:blocked,inner:blocked"
+	if got := strings.Join(seen, ","); got != want {
+		t.Fatalf("interceptors ran as %s, want %s", got, want)
+	}
+}
+
+func TestCallMetaReceivesResponseMeta(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.SetResponseMeta("region", "eu"); ctx.Result(1) })
+	s.Handle("e", func(ctx *Context) { ctx.SetResponseMeta("trace", "t1"); ctx.Error(protocol.InternalError(nil)) })
+	s.Handle("plain", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+
+	var cm CallMeta
+	if err := c.CallContext(ContextWithCallMeta(context.Background(), &cm), "x", nil, nil); err != nil || cm.Meta["region"] != "eu" {
+		t.Fatalf("x: %v, meta %v", err, cm.Meta)
+	}
+	// 错误响应同样携带 meta
+	c.CallContext(ContextWithCallMeta(context.Background(), &cm), "e", nil, nil)
+	if cm.Meta["trace"] != "t1" {
+		t.Fatalf("e: meta %v", cm.Meta)
+	}
+	c.CallContext(ContextWithCallMeta(context.Background(), &cm), "plain", nil, nil)
+	if cm.Meta != nil {
+		t.Fatalf("plain: meta %v, want nil", cm.Meta)
+	}
+}
@@ -0,0 +1,41 @@
+package jsonrpc2
+
+import "log"
+
+// Logger 是库内部使用的日志接口，可替换为任意结构化日志实现。
+type Logger interface {
+	Debugf(format string, args ...interface{})
+	Infof(format string, args ...interface{})
+	Errorf(format string, args ...interface{})
+}
+
+// stdLogger 是默认的 Logger，直接输出到标准库的 log 包
+type stdLogger struct{}
+
+func (stdLogger) Debugf(format string, args ...interface{}) { log.Printf(format, args...) }
+
+func (stdLogger) Infof(format string, args ...interface{}) { log.Printf(format, args...) }
+
+func (stdLogger) Errorf(format string, args ...interface{}) { log.Printf(format, args...) }
+
+// WithLogger 设置 Server 或 Client 使用的 Logger，默认输出到标准库的 log 包。
+func WithLogger(l Logger) Option {
+	return sharedOption{
+		server: func(s *Server) { s.logger = l },
+		client: func(c *Client) { c.logger = l },
+	}
+}
+
+// SetLogger 替换服务器使用的 Logger，可在运行时调用。
+func (s *Server) SetLogger(l Logger) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.logger = l
+}
+
+// log 返回服务器当前使用的 Logger
+func (s *Server) log() Logger {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.logger
+}
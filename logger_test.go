@@ -0,0 +1,49 @@
+package jsonrpc2
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// capLog 是记录所有日志的 Logger
+type capLog struct {
+	mu   sync.Mutex
+	msgs []string
+}
+
+func (l *capLog) add(format string, args ...interface{}) {
+	l.mu.Lock()
+	l.msgs = append(l.msgs, fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+func (l *capLog) Debugf(format string, args ...interface{}) { l.add(format, args...) }
+func (l *capLog) Infof(format string, args ...interface{})  { l.add(format, args...) }
+func (l *capLog) Errorf(format string, args ...interface{}) { l.add(format, args...) }
+
+func (l *capLog) messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.msgs...)
+}
+
+func TestWithLogger(t *testing.T) {
+	l := &capLog{}
+	s := NewServer(WithLogger(l))
+	if err := s.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	s.Close(context.Background())
+	deadline := time.Now().Add(time.Second)
+	for len(l.messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	msgs := l.messages()
+	if len(msgs) != 1 || !strings.Contains(msgs[0], "listener closed") {
+		t.Fatalf("logged %q", msgs)
+	}
+}
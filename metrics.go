@@ -0,0 +1,76 @@
+package jsonrpc2
+
+import (
+	"sync"
+	"time"
+)
+
+// MetricsSink 接收每个请求的处理结果，可对接 Prometheus 等监控系统。
+// errCode 为 0 表示请求成功，否则为响应中的错误码。实现必须可以被并发调用。
+type MetricsSink interface {
+	ObserveRequest(method string, dur time.Duration, errCode int)
+}
+
+// Metrics 返回一个记录请求指标的中间件：在整个后续处理链执行完毕后，
+// 将方法名、耗时以及最终的错误码交给 sink。作为全局中间件使用时应当最先注册，以覆盖其余中间件的耗时。
+func Metrics(sink MetricsSink) HandlerFunc {
+	return func(ctx *Context) {
+		start := time.Now()
+		ctx.Next()
+		code := 0
+		if err := ctx.GetResponseError(); err != nil {
+			code = err.Code
+		}
+		sink.ObserveRequest(ctx.Request.Method, time.Since(start), code)
+	}
+}
+
+// MethodStats 是 MemoryMetrics 中单个方法的统计数据。
+type MethodStats struct {
+	Requests      int           // 请求总数
+	Errors        map[int]int   // 按错误码统计的失败次数
+	TotalDuration time.Duration // 所有请求的耗时之和
+}
+
+// MemoryMetrics 是一个在内存中累计指标的 MetricsSink，适用于测试与调试。
+type MemoryMetrics struct {
+	mu      sync.Mutex
+	methods map[string]*MethodStats
+}
+
+// NewMemoryMetrics 创建一个空的 MemoryMetrics。
+func NewMemoryMetrics() *MemoryMetrics {
+	return &MemoryMetrics{methods: make(map[string]*MethodStats)}
+}
+
+// ObserveRequest 实现了 MetricsSink 接口。
+func (m *MemoryMetrics) ObserveRequest(method string, dur time.Duration, errCode int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.methods[method]
+	if !ok {
+		stats = &MethodStats{Errors: make(map[int]int)}
+		m.methods[method] = stats
+	}
+	stats.Requests++
+	stats.TotalDuration += dur
+	if errCode != 0 {
+		stats.Errors[errCode]++
+	}
+}
+
+// Stats 返回指定方法统计数据的副本，该方法尚无请求时返回零值。
+func (m *MemoryMetrics) Stats(method string) MethodStats {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	stats, ok := m.methods[method]
+	if !ok {
+		return MethodStats{Errors: map[int]int{}}
+	}
+	cp := *stats
+	cp.Errors = make(map[int]int, len(stats.Errors))
+	for code, n := range stats.Errors {
+		cp.Errors[code] = n
+	}
+	return cp
+}
@@ -0,0 +1,25 @@
+package jsonrpc2
+
+import (
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestMemoryMetrics(t *testing.T) {
+	m := NewMemoryMetrics()
+	s := NewServer()
+	s.Use(Metrics(m))
+	s.Handle("ok", func(ctx *Context) { ctx.Result(1) })
+	s.Handle("bad", func(ctx *Context) { ctx.Error(protocol.InvalidParamsError(nil)) })
+	c := startServer(t, s)
+	c.Call("ok", nil, nil, 5)
+	c.Call("ok", nil, nil, 5)
+	c.Call("bad", nil, nil, 5)
+	if st := m.Stats("ok"); st.Requests != 2 || len(st.Errors) != 0 {
+		t.Fatalf("ok: %+v", st)
+	}
+	if st := m.Stats("bad"); st.Requests != 1 || st.Errors[protocol.CodeInvalidParams] != 1 {
+		t.Fatalf("bad: %+v", st)
+	}
+}
@@ -0,0 +1,86 @@
+package jsonrpc2
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+	"golang.org/x/time/rate"
+)
+
+// rateLimitIdleTTL 是空闲 IP 的限流器被回收前的保留时间
+const rateLimitIdleTTL = 3 * time.Minute
+
+// visitor 记录单个远端 IP 的令牌桶与最近一次访问时间
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// RateLimit 返回一个按远端 IP 限流的中间件，每个 IP 拥有独立的令牌桶（速率 limit，容量 burst）。
+// 超出限制的请求会收到 "Rate limit exceeded" 服务端错误，处理链被中断。
+// 长时间空闲的 IP 的令牌桶会被定期回收，避免内存无限增长。
+func RateLimit(limit rate.Limit, burst int) HandlerFunc {
+	var (
+		mu        sync.Mutex
+		visitors  = make(map[string]*visitor)
+		lastSweep = time.Now()
+	)
+
+	allow := func(key string) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if now.Sub(lastSweep) > rateLimitIdleTTL {
+			for k, v := range visitors {
+				if now.Sub(v.lastSeen) > rateLimitIdleTTL {
+					delete(visitors, k)
+				}
+			}
+			lastSweep = now
+		}
+		v, ok := visitors[key]
+		if !ok {
+			v = &visitor{limiter: rate.NewLimiter(limit, burst)}
+			visitors[key] = v
+		}
+		v.lastSeen = now
+		return v.limiter.Allow()
+	}
+
+	return func(ctx *Context) {
+		if !allow(remoteIP(ctx.RemoteAddr())) {
+			ctx.Error(protocol.NewError(protocol.CodeServerError, "Rate limit exceeded", nil))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
+
+// remoteIP 从远端地址中提取 IP，无法解析时返回完整地址
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
+
+// MaxParamsBytes 返回一个限制请求 params 字节数的中间件，超过 n 字节的请求在进入处理器之前
+// 即以 InvalidParamsError 中断，避免对超大输入进行昂贵的解析。适合挂在面向公网的方法上。
+func MaxParamsBytes(n int) HandlerFunc {
+	return func(ctx *Context) {
+		if size := len(ctx.Request.Params); size > n {
+			ctx.Error(protocol.InvalidParamsError(fmt.Sprintf("params size %d exceeds limit of %d bytes", size, n)))
+			ctx.Abort()
+			return
+		}
+		ctx.Next()
+	}
+}
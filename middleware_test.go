@@ -0,0 +1,83 @@
+package jsonrpc2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestUseConcurrentWithCalls(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.Use(func(ctx *Context) { ctx.Next() })
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		if err := c.Call("x", nil, nil, 5); err != nil {
+			t.Fatal(err)
+		}
+	}
+	<-done
+}
+
+func TestUseOrderIsDeterministic(t *testing.T) {
+	s := NewServer()
+	var order []string
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	// 在 Handle 之后添加的全局中间件同样作用于已注册的方法，并按添加顺序执行
+	for _, name := range []string{"a", "b", "c"} {
+		name := name
+		s.Use(func(ctx *Context) {
+			order = append(order, name)
+			ctx.Next()
+		})
+	}
+	c := startServer(t, s)
+	if err := c.Call("x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(order, ","); got != "a,b,c" {
+		t.Fatalf("middleware order %s", got)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	s := NewServer()
+	s.Use(RateLimit(1, 2))
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+	limited := 0
+	for i := 0; i < 5; i++ {
+		err := c.Call("x", nil, nil, 5)
+		if err == nil {
+			continue
+		}
+		if e, ok := AsRPCError(err); !ok || e.Message != "Rate limit exceeded" {
+			t.Fatalf("got %v, want a rate limit error", err)
+		}
+		limited++
+	}
+	if limited != 3 {
+		t.Fatalf("%d of 5 calls were limited, want 3", limited)
+	}
+}
+
+func TestMaxParamsBytes(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", MaxParamsBytes(10), func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+	// "12345678" 编码后恰好 10 字节
+	if err := c.Call("x", "12345678", nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	err := c.Call("x", "123456789", nil, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("got %v, want InvalidParams", err)
+	}
+}
@@ -0,0 +1,99 @@
+package jsonrpc2
+
+import "time"
+
+// ServerOption 用于在 NewServer 时配置 Server。
+type ServerOption interface {
+	applyServer(s *Server)
+}
+
+// DialOption 用于在 Dial 时配置 Client。
+type DialOption interface {
+	applyClient(c *Client)
+}
+
+// Option 是同时适用于 Server 与 Client 的配置项。
+type Option interface {
+	ServerOption
+	DialOption
+}
+
+type serverOptionFunc func(s *Server)
+
+func (f serverOptionFunc) applyServer(s *Server) { f(s) }
+
+type dialOptionFunc func(c *Client)
+
+func (f dialOptionFunc) applyClient(c *Client) { f(c) }
+
+// sharedOption 分别持有对 Server 与 Client 的配置函数
+type sharedOption struct {
+	server func(s *Server)
+	client func(c *Client)
+}
+
+func (o sharedOption) applyServer(s *Server) { o.server(s) }
+
+func (o sharedOption) applyClient(c *Client) { o.client(c) }
+
+// WithMaxMessageBytes 限制单条消息的最大字节数，n <= 0 表示不限制（默认）。
+// 服务端收到超限消息时返回 InvalidRequest 错误并关闭连接；客户端收到超限响应时直接关闭连接。
+// 使用 GzipJSONCodec 时该上限同时作用于压缩数据与解压后的消息。
+func WithMaxMessageBytes(n int) Option {
+	return sharedOption{
+		server: func(s *Server) { s.maxMessageBytes = int64(n) },
+		client: func(c *Client) { c.maxMessageBytes = int64(n) },
+	}
+}
+
+// WithReadTimeout 为每条消息的读取设置期限，d <= 0 表示不限制（默认）。
+// 期限在读取每条消息之前刷新，因此长连接不会因总时长而超时，但对端在消息之间空闲
+// 或在一条消息中途停止发送超过 d 都会使读取失败并关闭连接。客户端上连接关闭时所有等待中的调用都会失败。
+func WithReadTimeout(d time.Duration) Option {
+	return sharedOption{
+		server: func(s *Server) { s.readTimeout = d },
+		client: func(c *Client) { c.readTimeout = d },
+	}
+}
+
+// WithWriteTimeout 为每条消息的写入设置期限，d <= 0 表示不限制（默认）。
+// 写入超时可能留下半条消息，此时连接会被关闭。
+func WithWriteTimeout(d time.Duration) Option {
+	return sharedOption{
+		server: func(s *Server) { s.writeTimeout = d },
+		client: func(c *Client) { c.writeTimeout = d },
+	}
+}
+
+// WithIDGenerator 设置客户端为 Call、Go 等调用生成请求 ID 的函数，例如生成 UUID 以避免暴露调用量。
+// 生成器在客户端内部锁中串行调用，返回值必须是字符串或数字；与仍在等待响应的调用重复的 ID 会使调用失败。
+func WithIDGenerator(gen func() interface{}) DialOption {
+	return dialOptionFunc(func(c *Client) {
+		c.idGen = gen
+	})
+}
+
+// WithMaxPending 限制同时等待响应的调用数量，n <= 0 表示不限制（默认）。
+// 达到上限后新的调用会阻塞，直到有调用完成；CallContext、Call 等同步调用的等待受 ctx 或 timeout 控制，
+// 期限已过时返回 ErrTimeout，ctx 被取消时返回 ctx.Err()。配合 WithFailOnMaxPending 可改为立即返回 ErrTooManyPending。
+func WithMaxPending(n int) DialOption {
+	return dialOptionFunc(func(c *Client) {
+		c.maxPending = n
+	})
+}
+
+// WithFailOnMaxPending 使达到 WithMaxPending 上限的调用立即返回 ErrTooManyPending 而不是阻塞。
+func WithFailOnMaxPending() DialOption {
+	return dialOptionFunc(func(c *Client) {
+		c.failOnMaxPending = true
+	})
+}
+
+// WithDefaultTimeout 设置 Call、CallWithMeta 等同步调用在 timeout 参数为 0 时使用的超时时间，
+// 从而只需在创建客户端时统一设置一次，未设置时为 5 秒。d 是实际时长（如 30 * time.Second），
+// 而调用时显式传入的非零 timeout 仍以秒计，并覆盖这里的默认值。
+func WithDefaultTimeout(d time.Duration) DialOption {
+	return dialOptionFunc(func(c *Client) {
+		c.defaultTimeout = d
+	})
+}
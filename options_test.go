@@ -0,0 +1,108 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestServerReadTimeoutClosesStalledConnection(t *testing.T) {
+	s := NewServer(WithReadTimeout(100 * time.Millisecond))
+	startServer(t, s)
+	conn, err := net.Dial("tcp", s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	// 消息写到一半后停止发送
+	conn.Write([]byte(`{"jsonrpc":"2.0","method":"p`))
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := conn.Read(make([]byte, 100)); err != io.EOF {
+		t.Fatalf("got %v, want the server to close the connection", err)
+	}
+}
+
+func TestClientReadTimeout(t *testing.T) {
+	s := NewServer()
+	s.Handle("slow", func(ctx *Context) { time.Sleep(300 * time.Millisecond); ctx.Result(1) })
+	startServer(t, s)
+	addr := s.listener.Addr().String()
+
+	c, err := Dial(addr, WithReadTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if err := c.Call("slow", nil, nil, 5); err == nil {
+		t.Fatal("expected the stalled read to fail the call")
+	}
+
+	// 只设置写超时时，间隔较长的调用不受影响
+	c2, err := Dial(addr, WithWriteTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	for i := 0; i < 3; i++ {
+		time.Sleep(60 * time.Millisecond)
+		if !c2.Ping() {
+			t.Fatal("ping failed")
+		}
+	}
+}
+
+func TestWithIDGenerator(t *testing.T) {
+	s := NewServer()
+	s.Handle("id", func(ctx *Context) { ctx.Result(string(ctx.Request.ID)) })
+	startServer(t, s)
+	n := 0
+	c, err := Dial(s.listener.Addr().String(), WithIDGenerator(func() interface{} { n++; return fmt.Sprintf("req-%d", n) }))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	for i := 1; i <= 3; i++ {
+		var r string
+		if err := c.Call("id", nil, &r, 5); err != nil || r != fmt.Sprintf(`"req-%d"`, i) {
+			t.Fatalf("Call = %v, id %s", err, r)
+		}
+	}
+	var r string
+	if err := c.CallContext(context.Background(), "id", nil, &r); err != nil || r != `"req-4"` {
+		t.Fatalf("CallContext = %v, id %s", err, r)
+	}
+}
+
+func TestWithDefaultTimeout(t *testing.T) {
+	s := NewServer()
+	s.Handle("slow", func(ctx *Context) { time.Sleep(300 * time.Millisecond); ctx.Result(1) })
+	startServer(t, s)
+	addr := s.listener.Addr().String()
+
+	c, err := Dial(addr, WithDefaultTimeout(100*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	start := time.Now()
+	if err := c.Call("slow", nil, nil, 0); !errors.Is(err, ErrTimeout) || time.Since(start) > 250*time.Millisecond {
+		t.Fatalf("got %v after %v, want ErrTimeout after the default timeout", err, time.Since(start))
+	}
+	// 显式的超时优先于默认值
+	if err := c.Call("slow", nil, nil, 3); err != nil {
+		t.Fatal(err)
+	}
+
+	c2, err := Dial(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	if d := c2.callTimeout(0); d != 5*time.Second {
+		t.Fatalf("default timeout = %v, want 5s", d)
+	}
+}
@@ -0,0 +1,169 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pool 维护到同一服务器的多个客户端连接，以轮询方式分发调用。
+// 断开的连接会在下次被选中时惰性地重新拨号替换。
+type Pool struct {
+	addr string
+	opts []DialOption
+
+	mu          sync.Mutex // 保护 clients、dialing、closed 与 onReconnect
+	clients     []*Client
+	dialing     []*poolDial // 正在重新拨号的位置，与 clients 一一对应
+	closed      bool
+	onReconnect func(c *Client) error
+	next        uint64 // 轮询计数器
+}
+
+// poolDial 是某个位置正在进行的重新拨号，done 关闭后 err 为拨号结果
+type poolDial struct {
+	done chan struct{}
+	err  error
+}
+
+// NewPool 创建一个包含 size 个连接的连接池，opts 会应用到每个连接上。
+func NewPool(addr string, size int, opts ...DialOption) (*Pool, error) {
+	if size <= 0 {
+		return nil, errors.New("jsonrpc2: pool size must be positive")
+	}
+	p := &Pool{
+		addr:    addr,
+		opts:    opts,
+		clients: make([]*Client, size),
+		dialing: make([]*poolDial, size),
+	}
+	for i := range p.clients {
+		client, err := Dial(addr, opts...)
+		if err != nil {
+			p.Close()
+			return nil, err
+		}
+		p.clients[i] = client
+	}
+	return p, nil
+}
+
+// get 轮询选出一个可用的客户端，若该连接已断开则重新拨号替换。
+// 拨号与 OnReconnect 回调在锁外进行，不会阻塞其他位置的调用；同一位置的并发调用方等待同一次拨号的结果。
+func (p *Pool) get() (*Client, error) {
+	idx := int(atomic.AddUint64(&p.next, 1) % uint64(len(p.clients)))
+
+	p.mu.Lock()
+	for {
+		if p.closed {
+			p.mu.Unlock()
+			return nil, errPoolClosed
+		}
+		client := p.clients[idx]
+		if client != nil && !client.isClosed() {
+			p.mu.Unlock()
+			return client, nil
+		}
+		d := p.dialing[idx]
+		if d == nil {
+			break
+		}
+		p.mu.Unlock()
+		<-d.done
+		if d.err != nil {
+			return nil, d.err
+		}
+		p.mu.Lock()
+	}
+	old := p.clients[idx]
+	p.clients[idx] = nil
+	d := &poolDial{done: make(chan struct{})}
+	p.dialing[idx] = d
+	onReconnect := p.onReconnect
+	p.mu.Unlock()
+
+	if old != nil {
+		old.Close()
+	}
+	client, err := Dial(p.addr, p.opts...)
+	if err == nil && onReconnect != nil {
+		if err = onReconnect(client); err != nil {
+			client.Close()
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.dialing[idx] = nil
+	if err == nil && p.closed {
+		client.Close()
+		err = errPoolClosed
+	}
+	if err == nil {
+		p.clients[idx] = client
+	}
+	d.err = err
+	close(d.done)
+	if err != nil {
+		return nil, err
+	}
+	return client, nil
+}
+
+// errPoolClosed 是池关闭后调用 Pool 的方法返回的错误
+var errPoolClosed = errors.New("jsonrpc2: pool is closed")
+
+// OnReconnect 设置断开的连接被重新拨号成功后调用的回调，例如重新建立订阅。
+// 回调在新连接投入使用之前执行，返回错误时该连接被关闭，本次重连视为失败，下次选中该位置时会再次重连。
+// 回调在池的锁之外执行，可以通过 c 发起完整的调用，其他位置的调用不受影响；回调返回之前，
+// 选中同一位置的调用方会等待它的结果，因此回调中不应通过 Pool 发起调用，以免选中同一位置而互相等待。
+func (p *Pool) OnReconnect(fn func(c *Client) error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onReconnect = fn
+}
+
+// Call 从池中选出一个连接发起同步调用，参数含义与 Client.Call 相同。
+func (p *Pool) Call(method string, args, reply interface{}, timeout time.Duration) error {
+	client, err := p.get()
+	if err != nil {
+		return err
+	}
+	return client.Call(method, args, reply, timeout)
+}
+
+// CallContext 从池中选出一个连接发起同步调用，参数含义与 Client.CallContext 相同。
+func (p *Pool) CallContext(ctx context.Context, method string, args, reply interface{}) error {
+	client, err := p.get()
+	if err != nil {
+		return err
+	}
+	return client.CallContext(ctx, method, args, reply)
+}
+
+// Notify 从池中选出一个连接发送通知。
+func (p *Pool) Notify(method string, args interface{}) error {
+	client, err := p.get()
+	if err != nil {
+		return err
+	}
+	return client.Notify(method, args)
+}
+
+// Close 关闭池中的所有连接。
+func (p *Pool) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return errPoolClosed
+	}
+	p.closed = true
+	for _, client := range p.clients {
+		if client != nil {
+			client.Close()
+		}
+	}
+	return nil
+}
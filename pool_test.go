@@ -0,0 +1,233 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countListener 统计 Accept 成功的连接数
+type countListener struct {
+	net.Listener
+	accepted int32
+}
+
+func (l *countListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		atomic.AddInt32(&l.accepted, 1)
+	}
+	return conn, err
+}
+
+// startCountingServer 在本地随机端口上启动 s，返回统计连接数的监听器
+func startCountingServer(t testing.TB, s *Server) *countListener {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cl := &countListener{Listener: l}
+	go s.Serve(cl)
+	t.Cleanup(func() { s.Close(context.Background()) })
+	return cl
+}
+
+// waitClosed 等待客户端察觉连接已断开
+func waitClosed(t *testing.T, c *Client) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !c.isClosed() {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not notice the closed connection")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPoolReplacesDeadConnection(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(7) })
+	l := startCountingServer(t, s)
+	p, err := NewPool(l.Addr().String(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	dead := p.clients[0]
+	dead.conn.Close()
+	waitClosed(t, dead)
+	for i := 0; i < 4; i++ {
+		var r int
+		if err := p.Call("x", nil, &r, 5); err != nil || r != 7 {
+			t.Fatalf("call %d: %v, reply %d", i, err, r)
+		}
+	}
+	if p.clients[0] == dead {
+		t.Fatal("dead connection was not replaced")
+	}
+	if n := atomic.LoadInt32(&l.accepted); n != 3 {
+		t.Fatalf("accepted %d connections, want 3", n)
+	}
+}
+
+func TestPoolRedialsOnceForConcurrentCallers(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(7) })
+	l := startCountingServer(t, s)
+	p, err := NewPool(l.Addr().String(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	dead := p.clients[0]
+	dead.conn.Close()
+	waitClosed(t, dead)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.Call("x", nil, nil, 5); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := atomic.LoadInt32(&l.accepted); n != 2 {
+		t.Fatalf("accepted %d connections, want 2", n)
+	}
+}
+
+func TestPoolCallAfterClose(t *testing.T) {
+	s := NewServer()
+	l := startCountingServer(t, s)
+	p, err := NewPool(l.Addr().String(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.Close()
+	if err := p.Call("x", nil, nil, 5); err != errPoolClosed {
+		t.Fatalf("got %v, want errPoolClosed", err)
+	}
+}
+
+func benchmarkParallelCalls(b *testing.B, call func() error) {
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if err := call(); err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func newBenchServer(b *testing.B) string {
+	s := NewServer()
+	s.Handle("echo", func(ctx *Context) { ctx.Result(ctx.Request.Params) })
+	return startCountingServer(b, s).Addr().String()
+}
+
+func BenchmarkSingleConnCall(b *testing.B) {
+	c, err := Dial(newBenchServer(b))
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	benchmarkParallelCalls(b, func() error { return c.Call("echo", []int{1}, nil, 5) })
+}
+
+func BenchmarkPoolCall(b *testing.B) {
+	p, err := NewPool(newBenchServer(b), 4)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer p.Close()
+	benchmarkParallelCalls(b, func() error { return p.Call("echo", []int{1}, nil, 5) })
+}
+
+func TestPoolOnReconnect(t *testing.T) {
+	s := NewServer()
+	s.Handle("sub", func(ctx *Context) { ctx.Result("ok") })
+	l := startCountingServer(t, s)
+	p, err := NewPool(l.Addr().String(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	calls := 0
+	fail := true
+	p.OnReconnect(func(c *Client) error {
+		calls++
+		var r string
+		if err := c.Call("sub", nil, &r, 5); err != nil || r != "ok" {
+			t.Errorf("call in OnReconnect: %v, reply %q", err, r)
+		}
+		if fail {
+			fail = false
+			return errors.New("boom")
+		}
+		return nil
+	})
+	var r string
+	if err := p.Call("sub", nil, &r, 5); err != nil || calls != 0 {
+		t.Fatal(err, calls)
+	}
+	dead := p.clients[0]
+	dead.Close()
+	if err := p.Call("sub", nil, &r, 5); err == nil || err.Error() != "boom" {
+		t.Fatalf("got %v, want boom", err)
+	}
+	if err := p.Call("sub", nil, &r, 5); err != nil || calls != 2 {
+		t.Fatal(err, calls)
+	}
+}
+
+func TestPoolOnReconnectDoesNotBlockOtherSlots(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(7) })
+	l := startCountingServer(t, s)
+	p, err := NewPool(l.Addr().String(), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	p.OnReconnect(func(c *Client) error {
+		close(entered)
+		<-release
+		return c.Call("x", nil, nil, 5)
+	})
+	dead := p.clients[0]
+	dead.Close()
+
+	// 轮询依次选中位置 1、0、1
+	if err := p.Call("x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	reconnected := make(chan error, 1)
+	go func() { reconnected <- p.Call("x", nil, nil, 5) }()
+	<-entered
+	if err := p.Call("x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-reconnected:
+		t.Fatalf("call on the reconnecting slot returned early: %v", err)
+	default:
+	}
+	close(release)
+	if err := <-reconnected; err != nil {
+		t.Fatal(err)
+	}
+}
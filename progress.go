@@ -0,0 +1,76 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// progressMethod 是进度通知的方法名
+const progressMethod = "$/progress"
+
+// progressParams 是进度通知的 params 结构，id 为所属请求的原始 id
+type progressParams struct {
+	ID    json.RawMessage `json:"id"`
+	Value interface{}     `json:"value"`
+}
+
+// Progress 在最终响应写回之前向客户端推送一条进度通知，
+// 通知的 method 为 $/progress，params 为 {"id": 请求 id, "value": value}。
+// 进度通知与响应共用连接的写锁，因此总是先于最终响应到达。通知请求没有 id，Copy 得到的副本
+// 不能保证先于最终响应发送，二者调用都会返回错误。
+func (c *Context) Progress(value interface{}) error {
+	if c.detached {
+		return errors.New("jsonrpc2: progress requires the original context")
+	}
+	if len(c.Request.ID) == 0 {
+		return errors.New("jsonrpc2: progress requires a request id")
+	}
+	if c.sconn == nil {
+		return errors.New("jsonrpc2: progress requires a connection")
+	}
+	raw, err := marshalValue(c.sconn.marshal, progressParams{ID: c.Request.ID, Value: value})
+	if err != nil {
+		return err
+	}
+	return c.sconn.notify(progressMethod, raw)
+}
+
+// OnProgress 为指定 id 的调用注册进度处理器，必须在发起该调用（例如 CallWithID、GoWithID）之前注册。
+// 处理器在接收循环中按到达顺序同步调用，因此所有进度通知都会在调用返回之前处理完毕；
+// 调用收到响应或被取消后处理器会被自动移除。
+func (c *Client) OnProgress(id interface{}, handler func(value json.RawMessage)) error {
+	idKey, err := idToKey(id)
+	if err != nil {
+		return err
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.progressHandlers == nil {
+		c.progressHandlers = make(map[string]func(value json.RawMessage))
+	}
+	c.progressHandlers[idKey] = handler
+	return nil
+}
+
+// handleProgress 将进度通知交给对应调用的处理器，返回是否找到了处理器
+func (c *Client) handleProgress(params json.RawMessage) bool {
+	var p struct {
+		ID    interface{}     `json:"id"`
+		Value json.RawMessage `json:"value"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return false
+	}
+	idKey, err := idToKey(p.ID)
+	if err != nil {
+		return false
+	}
+	c.mutex.Lock()
+	handler := c.progressHandlers[idKey]
+	c.mutex.Unlock()
+	if handler == nil {
+		return false
+	}
+	handler(p.Value)
+	return true
+}
@@ -0,0 +1,47 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestProgress(t *testing.T) {
+	s := NewServer()
+	s.Handle("job", func(ctx *Context) {
+		for i := 1; i <= 3; i++ {
+			if err := ctx.Progress(map[string]int{"pct": i * 33}); err != nil {
+				t.Error(err)
+			}
+		}
+		ctx.Result("done")
+	})
+	c := startServer(t, s)
+	var got []string
+	c.OnProgress("job-1", func(v json.RawMessage) { got = append(got, string(v)) })
+	var r string
+	if err := c.CallWithID("job-1", "job", nil, &r, 5); err != nil || r != "done" {
+		t.Fatalf("CallWithID = %v, reply %q", err, r)
+	}
+	if len(got) != 3 || got[2] != `{"pct":99}` {
+		t.Fatalf("progress values %v", got)
+	}
+	if len(c.progressHandlers) != 0 {
+		t.Fatal("progress handler was not removed after the call completed")
+	}
+}
+
+func TestProgressOnCopyFails(t *testing.T) {
+	s := NewServer()
+	errc := make(chan error, 1)
+	s.Handle("job", func(ctx *Context) {
+		errc <- ctx.Copy().Progress(1)
+		ctx.Result("done")
+	})
+	c := startServer(t, s)
+	if err := c.Call("job", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-errc; err == nil {
+		t.Fatal("Progress on a copied context should fail")
+	}
+}
@@ -1,5 +1,7 @@
 package protocol
 
+import "time"
+
 // 标准 JSON-RPC 2.0 错误码
 const (
 	CodeParseError     = -32700
@@ -9,6 +11,15 @@ const (
 	CodeInternalError  = -32603
 )
 
+// 实现自定义的服务端错误码（-32000 至 -32099）
+const (
+	CodeServerError        = -32000
+	CodeUnauthorized       = -32001
+	CodeDeadlineExceeded   = -32002
+	CodeUnavailable        = -32003
+	CodeUnsupportedVersion = -32004
+)
+
 func NewError(code int, message string, data interface{}) *ErrorObject {
 	return &ErrorObject{Code: code, Message: message, Data: data}
 }
@@ -32,3 +43,24 @@ func InvalidParamsError(data interface{}) *ErrorObject {
 func InternalError(data interface{}) *ErrorObject {
 	return NewError(CodeInternalError, "Internal error", data)
 }
+
+func UnauthorizedError(data interface{}) *ErrorObject {
+	return NewError(CodeUnauthorized, "Unauthorized", data)
+}
+
+func DeadlineExceededError(data interface{}) *ErrorObject {
+	return NewError(CodeDeadlineExceeded, "Deadline exceeded", data)
+}
+
+// UnavailableError 表示方法暂时不可用（例如被限流），data 为 {"retry_after_ms": N}，
+// 提示客户端至少等待 retryAfter 后再重试，客户端可以通过 jsonrpc2.RetryAfter 读取。
+func UnavailableError(retryAfter time.Duration) *ErrorObject {
+	if retryAfter < 0 {
+		retryAfter = 0
+	}
+	return NewError(CodeUnavailable, "Service unavailable", map[string]int64{"retry_after_ms": retryAfter.Milliseconds()})
+}
+
+func UnsupportedVersionError(data interface{}) *ErrorObject {
+	return NewError(CodeUnsupportedVersion, "Unsupported version", data)
+}
@@ -7,15 +7,23 @@ type Request struct {
 	Jsonrpc string          `json:"jsonrpc"`
 	Method  string          `json:"method"`
 	Params  json.RawMessage `json:"params"`
-	ID      interface{}     `json:"id"`
+	// ID 保留报文中的原始字节，服务端原样回写，不会把 42 变成 42.0 或科学计数法；
+	// 报文中没有 id 成员时为空，表示这是一个通知（注意 "id": null 不是通知）
+	ID json.RawMessage `json:"id,omitempty"`
+	// Meta 携带与业务参数无关的元数据（如关联 ID、租户、鉴权令牌），为空时不会出现在报文中
+	Meta map[string]string `json:"meta,omitempty"`
 }
 
 // Response 代表一个 JSON-RPC 2.0 响应对象
+// 服务端将任意 Go 值赋给 Result 进行编码；解码时 Result 保存为 json.RawMessage，
+// 调用方可直接将原始字节解析到目标类型，避免二次编解码。
 type Response struct {
 	Jsonrpc string       `json:"jsonrpc"`
 	Result  interface{}  `json:"result,omitempty"`
 	Error   *ErrorObject `json:"error,omitempty"`
 	ID      interface{}  `json:"id"`
+	// Meta 携带服务端设置的响应元数据，为空时不会出现在报文中
+	Meta map[string]string `json:"meta,omitempty"`
 }
 
 // ErrorObject 代表响应中的错误详情
@@ -29,3 +37,48 @@ type ErrorObject struct {
 func (e *ErrorObject) Error() string {
 	return e.Message
 }
+
+// MarshalJSON 按规范编码响应：成功响应始终包含 result 成员（即使为 null），
+// 错误响应只包含 error 成员，二者不会同时出现。
+func (r Response) MarshalJSON() ([]byte, error) {
+	if r.Error != nil {
+		return json.Marshal(struct {
+			Jsonrpc string            `json:"jsonrpc"`
+			Error   *ErrorObject      `json:"error"`
+			ID      interface{}       `json:"id"`
+			Meta    map[string]string `json:"meta,omitempty"`
+		}{r.Jsonrpc, r.Error, r.ID, r.Meta})
+	}
+	return json.Marshal(struct {
+		Jsonrpc string            `json:"jsonrpc"`
+		Result  interface{}       `json:"result"`
+		ID      interface{}       `json:"id"`
+		Meta    map[string]string `json:"meta,omitempty"`
+	}{r.Jsonrpc, r.Result, r.ID, r.Meta})
+}
+
+// responseWire 是 Response 在解码时使用的报文结构
+type responseWire struct {
+	Jsonrpc string            `json:"jsonrpc"`
+	Result  json.RawMessage   `json:"result"`
+	Error   *ErrorObject      `json:"error"`
+	ID      interface{}       `json:"id"`
+	Meta    map[string]string `json:"meta"`
+}
+
+// UnmarshalJSON 将 result 成员保留为 json.RawMessage；报文中没有 result 时 Result 为 nil。
+func (r *Response) UnmarshalJSON(data []byte) error {
+	var wire responseWire
+	if err := json.Unmarshal(data, &wire); err != nil {
+		return err
+	}
+	r.Jsonrpc = wire.Jsonrpc
+	r.Result = nil
+	if wire.Result != nil {
+		r.Result = wire.Result
+	}
+	r.Error = wire.Error
+	r.ID = wire.ID
+	r.Meta = wire.Meta
+	return nil
+}
@@ -0,0 +1,73 @@
+package protocol
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestResponseUnmarshalKeepsRawResult(t *testing.T) {
+	tests := []struct {
+		in   string
+		want interface{}
+	}{
+		{`{"jsonrpc":"2.0","result":{"a":[1,2]},"id":1}`, `{"a":[1,2]}`},
+		{`{"jsonrpc":"2.0","result":null,"id":1}`, `null`},
+		{`{"jsonrpc":"2.0","error":{"code":-32601,"message":"Method not found"},"id":1}`, nil},
+	}
+	for _, tt := range tests {
+		var r Response
+		if err := json.Unmarshal([]byte(tt.in), &r); err != nil {
+			t.Fatalf("%s: %v", tt.in, err)
+		}
+		if tt.want == nil {
+			if r.Result != nil {
+				t.Errorf("%s: Result = %v, want nil", tt.in, r.Result)
+			}
+			continue
+		}
+		raw, ok := r.Result.(json.RawMessage)
+		if !ok || string(raw) != tt.want {
+			t.Errorf("%s: Result = %#v, want raw %s", tt.in, r.Result, tt.want)
+		}
+	}
+}
+
+func BenchmarkResponseUnmarshal(b *testing.B) {
+	data := []byte(`{"jsonrpc":"2.0","result":{"name":"bob","tags":["a","b","c"],"score":42},"id":1}`)
+	var out struct {
+		Name  string   `json:"name"`
+		Tags  []string `json:"tags"`
+		Score int      `json:"score"`
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		var r Response
+		if err := json.Unmarshal(data, &r); err != nil {
+			b.Fatal(err)
+		}
+		if err := json.Unmarshal(r.Result.(json.RawMessage), &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestResponseMarshalNullResult(t *testing.T) {
+	tests := []struct {
+		resp *Response
+		want string
+	}{
+		{&Response{Jsonrpc: "2.0", ID: 1}, `{"jsonrpc":"2.0","result":null,"id":1}`},
+		{&Response{Jsonrpc: "2.0", ID: 1, Result: 5}, `{"jsonrpc":"2.0","result":5,"id":1}`},
+		// 同时设置了 result 与 error 时只编码 error
+		{&Response{Jsonrpc: "2.0", ID: 1, Result: 5, Error: InternalError(nil)}, `{"jsonrpc":"2.0","error":{"code":-32603,"message":"Internal error"},"id":1}`},
+	}
+	for _, tt := range tests {
+		b, err := json.Marshal(tt.resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.want {
+			t.Errorf("got %s, want %s", b, tt.want)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// RetryPolicy 描述 CallRetry 的重试策略。
+type RetryPolicy struct {
+	MaxAttempts int           // 最大尝试次数（包含首次调用），<= 0 时视为 1
+	Backoff     time.Duration // 第一次重试前的等待时间，之后每次翻倍
+	MaxBackoff  time.Duration // 等待时间的上限，0 表示不限制
+	Timeout     time.Duration // 单次尝试的超时时间，含义与 Call 的 timeout 参数相同
+}
+
+// CallRetry 发起一个同步调用，并在发生连接层错误时按 policy 重试。
+// 仅应用于幂等方法：服务端返回的 ErrorObject 以及结果解析错误不会触发重试。
+// 每次尝试都使用新的请求 ID，超时尝试的迟到响应不会被误认为后续尝试的结果。
+// 所有尝试都使用同一个连接，连接断开后客户端无法恢复，因此遇到 ErrShutdown 时立即返回；
+// 需要在连接断开后重新拨号重试时使用 Pool.CallRetry。
+func (c *Client) CallRetry(method string, args, reply interface{}, policy RetryPolicy) error {
+	return retry(policy, func() error {
+		return c.Call(method, args, reply, policy.Timeout)
+	}, func(err error) bool {
+		return !errors.Is(err, ErrShutdown) && isRetryable(err)
+	})
+}
+
+// CallRetry 从池中选出连接发起同步调用，并在发生连接层错误时按 policy 重试，规则与 Client.CallRetry 相同。
+// 每次尝试都重新从池中取连接，已断开的连接会被重新拨号替换，因此连接断开后的重试可以在新连接上成功。
+func (p *Pool) CallRetry(method string, args, reply interface{}, policy RetryPolicy) error {
+	return retry(policy, func() error {
+		return p.Call(method, args, reply, policy.Timeout)
+	}, isRetryable)
+}
+
+// retry 按 policy 反复执行 attempt，直到成功、遇到 retryable 判定为不可重试的错误或次数用尽
+func retry(policy RetryPolicy, attempt func() error, retryable func(err error) bool) error {
+	attempts := policy.MaxAttempts
+	if attempts <= 0 {
+		attempts = 1
+	}
+	backoff := policy.Backoff
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 && backoff > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+			if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+				backoff = policy.MaxBackoff
+			}
+		}
+		err = attempt()
+		if err == nil || !retryable(err) {
+			return err
+		}
+	}
+	return err
+}
+
+// isRetryable 判断错误是否属于可重试的连接层错误
+func isRetryable(err error) bool {
+	if _, ok := AsRPCError(err); ok {
+		return false
+	}
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+		return false
+	}
+	if errors.Is(err, ErrTimeout) || errors.Is(err, ErrShutdown) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,102 @@
+package jsonrpc2
+
+import (
+	"context"
+	"io"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestPoolCallRetryRedialsAfterConnectionFailure(t *testing.T) {
+	s := NewServer()
+	var calls int32
+	s.Handle("x", func(ctx *Context) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			// 第一次调用时断开连接，客户端收到连接错误
+			ctx.Conn.Close()
+			return
+		}
+		ctx.Result(7)
+	})
+	startServer(t, s)
+	p, err := NewPool(s.listener.Addr().String(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer p.Close()
+
+	var r int
+	err = p.CallRetry("x", nil, &r, RetryPolicy{MaxAttempts: 3, Backoff: 10 * time.Millisecond, Timeout: 5})
+	if err != nil || r != 7 {
+		t.Fatalf("CallRetry = %v, reply %d", err, r)
+	}
+	if n := atomic.LoadInt32(&calls); n != 2 {
+		t.Fatalf("handler called %d times, want 2", n)
+	}
+}
+
+func TestClientCallRetryStopsOnShutdown(t *testing.T) {
+	s := NewServer()
+	var calls int32
+	s.Handle("x", func(ctx *Context) {
+		atomic.AddInt32(&calls, 1)
+		ctx.Conn.Close()
+	})
+	c := startServer(t, s)
+
+	start := time.Now()
+	err := c.CallRetry("x", nil, nil, RetryPolicy{MaxAttempts: 5, Backoff: 50 * time.Millisecond, Timeout: 5})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("handler called %d times, want 1", n)
+	}
+	// 连接断开后的第二次尝试以 ErrShutdown 立即结束，不会继续退避重试
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("CallRetry took %v", elapsed)
+	}
+}
+
+func TestClientCallRetryRetriesTransientErrors(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(7) })
+	c := startServer(t, s)
+	attempts := 0
+	c.Use(func(next Invoker) Invoker {
+		return func(ctx context.Context, method string, args, reply interface{}) error {
+			attempts++
+			if attempts == 1 {
+				return io.EOF
+			}
+			return next(ctx, method, args, reply)
+		}
+	})
+	var r int
+	if err := c.CallRetry("x", nil, &r, RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}); err != nil || r != 7 {
+		t.Fatalf("CallRetry = %v, reply %d", err, r)
+	}
+	if attempts != 2 {
+		t.Fatalf("%d attempts, want 2", attempts)
+	}
+}
+
+func TestCallRetryDoesNotRetryServerErrors(t *testing.T) {
+	s := NewServer()
+	var calls int32
+	s.Handle("x", func(ctx *Context) {
+		atomic.AddInt32(&calls, 1)
+		ctx.Error(protocol.InvalidParamsError(nil))
+	})
+	c := startServer(t, s)
+	err := c.CallRetry("x", nil, nil, RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond})
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("got %v, want InvalidParams", err)
+	}
+	if n := atomic.LoadInt32(&calls); n != 1 {
+		t.Fatalf("handler called %d times, want 1", n)
+	}
+}
@@ -1,38 +1,251 @@
 package jsonrpc2
 
-import "sync"
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
 
 // handlerEntry 直接存储处理器链
+// entry 创建后不再修改，全局中间件变化时会整体替换，保证并发读取安全
 type handlerEntry struct {
-	chain []HandlerFunc
+	chain    []HandlerFunc // 路由自身的中间件与处理器
+	combined []HandlerFunc // 全局中间件 + chain，请求分发时直接使用
+	bare     bool          // 内置的保活方法：combined 即 chain，不经过任何中间件与鉴权
 }
 
 type router struct {
 	mu       sync.RWMutex
 	handlers map[string]*handlerEntry
+	global   []HandlerFunc // 全局中间件
+	aliases  map[string]*alias
+
+	// namespaces 保存按点分前缀挂载的中间件，对该前缀下的所有方法生效
+	namespaces map[string][]HandlerFunc
+	// fallback 是未注册方法使用的默认处理链，为空时返回 MethodNotFound
+	fallback *handlerEntry
+
+	// caseInsensitive 为 true 时方法名在注册与查找前统一转换为小写
+	caseInsensitive bool
+
+	// deprecated 在每个别名第一次被使用时调用，可为空
+	deprecated func(oldName, newName string)
+}
+
+// alias 将旧方法名指向新方法名
+type alias struct {
+	target string
+	warned sync.Once
 }
 
 func newRouter() *router {
 	return &router{
 		handlers: make(map[string]*handlerEntry),
+		aliases:  make(map[string]*alias),
+	}
+}
+
+// normalize 返回方法名在路由表中的 key
+func (r *router) normalize(method string) string {
+	if r.caseInsensitive {
+		return strings.ToLower(method)
+	}
+	return method
+}
+
+// reserved 报告 method 是否使用了规范保留的 rpc. 前缀且不是内置方法
+func (r *router) reserved(method string) bool {
+	method = r.normalize(method)
+	return strings.HasPrefix(method, reservedPrefix) && !builtinMethods[method]
+}
+
+// newEntry 根据当前全局中间件与命名空间中间件构建 entry，调用方需持有写锁。
+// 组合顺序为：全局中间件 → 由浅到深各级命名空间的中间件 → chain。
+func (r *router) newEntry(method string, chain []HandlerFunc) *handlerEntry {
+	combined := make([]HandlerFunc, 0, len(r.global)+len(chain))
+	combined = append(combined, r.global...)
+	for i := 0; i < len(method); i++ {
+		if method[i] == '.' {
+			combined = append(combined, r.namespaces[method[:i]]...)
+		}
+	}
+	combined = append(combined, chain...)
+	return &handlerEntry{
+		chain:    chain,
+		combined: combined,
+	}
+}
+
+// use 追加全局中间件并重建所有方法的组合处理链
+// 已取得旧 entry 的请求继续使用旧的处理链，之后的请求使用新的处理链
+func (r *router) use(middlewares ...HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	global := make([]HandlerFunc, 0, len(r.global)+len(middlewares))
+	global = append(global, r.global...)
+	r.global = append(global, middlewares...)
+	for method, entry := range r.handlers {
+		if !entry.bare {
+			r.handlers[method] = r.newEntry(method, entry.chain)
+		}
+	}
+	if r.fallback != nil {
+		r.fallback = r.newEntry("", r.fallback.chain)
+	}
+}
+
+// setDefault 设置未注册方法使用的默认处理链
+func (r *router) setDefault(handlers ...HandlerFunc) {
+	if len(handlers) == 0 {
+		panic("jsonrpc2: handler chain cannot be empty")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.fallback = r.newEntry("", handlers)
+}
+
+// defaultEntry 返回默认处理链，未设置时返回 nil
+func (r *router) defaultEntry() *handlerEntry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.fallback
+}
+
+// useNamespace 为命名空间追加中间件并重建所有方法的组合处理链
+func (r *router) useNamespace(namespace string, middlewares ...HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	namespace = r.normalize(namespace)
+	if r.namespaces == nil {
+		r.namespaces = make(map[string][]HandlerFunc)
+	}
+	existing := r.namespaces[namespace]
+	mws := make([]HandlerFunc, 0, len(existing)+len(middlewares))
+	mws = append(mws, existing...)
+	r.namespaces[namespace] = append(mws, middlewares...)
+	for method, entry := range r.handlers {
+		if !entry.bare {
+			r.handlers[method] = r.newEntry(method, entry.chain)
+		}
 	}
 }
 
 // add 接收一个或多个 HandlerFunc，它们共同构成一个处理链
+// 若方法已注册，新的处理链会直接替换旧的处理链
 func (r *router) add(method string, handlers ...HandlerFunc) {
 	if len(handlers) == 0 {
 		panic("jsonrpc2: handler chain cannot be empty")
 	}
 	r.mu.Lock()
 	defer r.mu.Unlock()
-	r.handlers[method] = &handlerEntry{
-		chain: handlers,
+	method = r.normalize(method)
+	r.handlers[method] = r.newEntry(method, handlers)
+}
+
+// addDefault 仅在方法尚未注册时注册处理链，用于内置方法，使先于它们注册的同名方法不被覆盖
+func (r *router) addDefault(method string, handlers ...HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	method = r.normalize(method)
+	if _, ok := r.handlers[method]; ok {
+		return
+	}
+	r.handlers[method] = r.newEntry(method, handlers)
+}
+
+// addBare 在方法尚未注册时注册一个不经过中间件的处理链，之后用 add 注册同名方法会替换为普通的处理链
+func (r *router) addBare(method string, handlers ...HandlerFunc) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	method = r.normalize(method)
+	if _, ok := r.handlers[method]; ok {
+		return
+	}
+	r.handlers[method] = &handlerEntry{chain: handlers, combined: handlers, bare: true}
+}
+
+// remove 移除方法的处理链，返回该方法此前是否已注册
+func (r *router) remove(method string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	method = r.normalize(method)
+	if _, ok := r.handlers[method]; !ok {
+		return false
 	}
+	delete(r.handlers, method)
+	return true
 }
 
+// addAlias 使对 oldName 的调用分发到 newName 的处理链，重复设置会替换之前的指向。
+// 别名可以链式指向另一个别名，形成环的别名会被拒绝。
+func (r *router) addAlias(oldName, newName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	oldName, newName = r.normalize(oldName), r.normalize(newName)
+	for name := newName; ; {
+		if name == oldName {
+			return fmt.Errorf("jsonrpc2: alias %q -> %q would create a cycle", oldName, newName)
+		}
+		a, ok := r.aliases[name]
+		if !ok {
+			break
+		}
+		name = a.target
+	}
+	r.aliases[oldName] = &alias{target: newName}
+	return nil
+}
+
+// resolve 沿别名链返回 method 实际分发到的方法名，不记录弃用日志
+func (r *router) resolve(method string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	method = r.normalize(method)
+	for hops := 0; hops <= len(r.aliases); hops++ {
+		if _, ok := r.handlers[method]; ok {
+			return method
+		}
+		a, ok := r.aliases[method]
+		if !ok {
+			break
+		}
+		method = a.target
+	}
+	return method
+}
+
+// find 查找方法的处理链。已注册的方法优先于同名别名，别名按链条逐级解析。
 func (r *router) find(method string) (*handlerEntry, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
-	entry, ok := r.handlers[method]
-	return entry, ok
+	method = r.normalize(method)
+	// 别名在添加时已保证无环，跳数上限只是额外的保护
+	for hops := 0; hops <= len(r.aliases); hops++ {
+		if entry, ok := r.handlers[method]; ok {
+			return entry, true
+		}
+		a, ok := r.aliases[method]
+		if !ok {
+			return nil, false
+		}
+		if r.deprecated != nil {
+			oldName := method
+			a.warned.Do(func() { r.deprecated(oldName, a.target) })
+		}
+		method = a.target
+	}
+	return nil, false
+}
+
+// methods 返回所有已注册方法名，按字典序排序；大小写不敏感模式下返回的是小写形式
+func (r *router) methods() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.handlers))
+	for method := range r.handlers {
+		names = append(names, method)
+	}
+	sort.Strings(names)
+	return names
 }
@@ -0,0 +1,201 @@
+package jsonrpc2
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestMethodsAndDiscover(t *testing.T) {
+	s := NewServer()
+	s.Handle("b", func(ctx *Context) {})
+	s.Handle("a", func(ctx *Context) {})
+	c := startServer(t, s)
+	var m []string
+	if err := c.Call("rpc.discover", nil, &m, 5); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"a", "b", "ping", "rpc.discover", "rpc.health"}
+	if strings.Join(m, ",") != strings.Join(want, ",") {
+		t.Fatalf("rpc.discover = %v, want %v", m, want)
+	}
+	if got := s.Methods(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("Methods = %v, want %v", got, want)
+	}
+}
+
+func TestDeregister(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+	if err := c.Call("x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	if !s.Deregister("x") {
+		t.Fatal("Deregister of a registered method returned false")
+	}
+	if s.Deregister("x") {
+		t.Fatal("second Deregister returned true")
+	}
+	if e, ok := AsRPCError(c.Call("x", nil, nil, 5)); !ok || e.Code != protocol.CodeMethodNotFound {
+		t.Fatalf("got %v, want MethodNotFound", e)
+	}
+	// 注销后可以重新注册
+	s.Handle("x", func(ctx *Context) { ctx.Result(2) })
+	var r int
+	if err := c.Call("x", nil, &r, 5); err != nil || r != 2 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+}
+
+func TestAlias(t *testing.T) {
+	l := &capLog{}
+	s := NewServer(WithLogger(l))
+	s.Handle("v2.add", func(ctx *Context) { ctx.Result("v2") })
+	if err := s.Alias("v1.add", "v2.add"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Alias("add", "v1.add"); err != nil {
+		t.Fatal(err)
+	}
+	if s.Alias("x", "x") == nil {
+		t.Fatal("self alias accepted")
+	}
+	if s.Alias("v2.add", "add") == nil {
+		t.Fatal("alias cycle accepted")
+	}
+	c := startServer(t, s)
+	for i := 0; i < 3; i++ {
+		var r string
+		if err := c.Call("add", nil, &r, 5); err != nil || r != "v2" {
+			t.Fatalf("Call = %v, reply %q", err, r)
+		}
+	}
+	if err := c.Call("zz", nil, nil, 5); err == nil {
+		t.Fatal("expected method not found")
+	}
+	warned := 0
+	for _, m := range l.messages() {
+		if strings.Contains(m, `method "add" is deprecated`) {
+			warned++
+		}
+	}
+	if warned != 1 {
+		t.Fatalf("deprecation logged %d times, want once: %q", warned, l.messages())
+	}
+}
+
+func TestCaseInsensitiveMethods(t *testing.T) {
+	s := NewServer(WithCaseInsensitiveMethods())
+	s.Handle("Arith.Add", func(ctx *Context) { ctx.Result(1) })
+	if err := s.Alias("Old.Add", "ARITH.add"); err != nil {
+		t.Fatal(err)
+	}
+	c := startServer(t, s)
+	for _, m := range []string{"arith.add", "ARITH.ADD", "Arith.Add", "old.add", "PING"} {
+		if err := c.Call(m, nil, nil, 5); err != nil {
+			t.Fatalf("%s: %v", m, err)
+		}
+	}
+
+	s2 := NewServer()
+	s2.Handle("Arith.Add", func(ctx *Context) { ctx.Result(1) })
+	if _, ok := s2.router.find("arith.add"); ok {
+		t.Fatal("methods are case sensitive by default")
+	}
+}
+
+func TestUseNamespace(t *testing.T) {
+	s := NewServer()
+	var order []string
+	mk := func(n string) HandlerFunc { return func(ctx *Context) { order = append(order, n); ctx.Next() } }
+	s.Use(mk("global"))
+	s.UseNamespace("account", mk("account"))
+	s.Handle("account.billing.charge", mk("route"), func(ctx *Context) { ctx.Result(1) })
+	// 注册方法之后添加的命名空间中间件同样生效
+	s.UseNamespace("account.billing", mk("billing"))
+	// 命名空间按 . 分隔的整段匹配，acc 不匹配 account 或 accountx
+	s.UseNamespace("acc", mk("acc"))
+	s.Handle("accountx.y", func(ctx *Context) { ctx.Result(1) })
+
+	s.TestContext("account.billing.charge", nil)
+	if got := strings.Join(order, ","); got != "global,account,billing,route" {
+		t.Fatalf("order = %s", got)
+	}
+	order = nil
+	s.TestContext("accountx.y", nil)
+	if got := strings.Join(order, ","); got != "global" {
+		t.Fatalf("order = %s", got)
+	}
+}
+
+func TestHandleDefault(t *testing.T) {
+	s := NewServer()
+	var order []string
+	s.Use(func(ctx *Context) { order = append(order, "g"); ctx.Next() })
+	s.Handle("known", func(ctx *Context) { ctx.Result("own") })
+	s.HandleDefault(func(ctx *Context) { ctx.Result("default:" + ctx.Request.Method) })
+	if r := s.TestContext("known", nil).GetResponseResult(); r != "own" {
+		t.Fatalf("known = %v", r)
+	}
+	if r := s.TestContext("foo.bar", nil).GetResponseResult(); r != "default:foo.bar" {
+		t.Fatalf("foo.bar = %v", r)
+	}
+	// 全局中间件同样作用于默认处理器
+	if len(order) != 2 {
+		t.Fatalf("global middleware ran %d times, want 2", len(order))
+	}
+	c := startServer(t, s)
+	var r string
+	if err := c.Call("zzz", nil, &r, 5); err != nil || r != "default:zzz" {
+		t.Fatalf("Call = %v, reply %q", err, r)
+	}
+}
+
+func TestReservedRPCPrefix(t *testing.T) {
+	s := NewServer()
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("Handle accepted a reserved rpc. method")
+			}
+		}()
+		s.Handle("rpc.custom", func(ctx *Context) {})
+	}()
+	if err := s.Alias("rpc.old", "x"); err == nil {
+		t.Fatal("Alias accepted a reserved rpc. name")
+	}
+	s.HandleDefault(func(ctx *Context) { ctx.Result("default") })
+	c := startServer(t, s)
+
+	var m []string
+	if err := c.Call("rpc.discover", nil, &m, 5); err != nil || len(m) == 0 {
+		t.Fatalf("rpc.discover = %v, %v", err, m)
+	}
+	// 未注册的 rpc. 方法不交给默认处理器
+	err := c.Call("rpc.nope", nil, nil, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeMethodNotFound || e.Data != "rpc.nope is a reserved method name" {
+		t.Fatalf("rpc.nope: got %v", err)
+	}
+	var r string
+	if err := c.Call("other", nil, &r, 5); err != nil || r != "default" {
+		t.Fatalf("other = %v, reply %q", err, r)
+	}
+}
+
+func TestReplaceBuiltinsBeforeListen(t *testing.T) {
+	s := NewServer()
+	s.Handle("rpc.discover", func(ctx *Context) { ctx.Result([]string{"custom"}) })
+	s.Handle("rpc.health", func(ctx *Context) { ctx.Result("custom health") })
+	c := startServer(t, s)
+
+	var m []string
+	if err := c.Call("rpc.discover", nil, &m, 5); err != nil || len(m) != 1 || m[0] != "custom" {
+		t.Fatalf("rpc.discover = %v, %v, want the custom handler", err, m)
+	}
+	var h string
+	if err := c.Call("rpc.health", nil, &h, 5); err != nil || h != "custom health" {
+		t.Fatalf("rpc.health = %v, %q, want the custom handler", err, h)
+	}
+}
@@ -2,89 +2,502 @@ package jsonrpc2
 
 import (
 	"context"
+	"crypto/tls"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
-	"log"
 	"net"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/kyle-cao/jsonrpc2/protocol"
 )
 
 type Server struct {
-	router            *router
-	mu                sync.Mutex // 保护 listener 字段
-	listener          net.Listener
-	wg                sync.WaitGroup // 用于追踪活动的连接处理 goroutine
-	globalMiddlewares []HandlerFunc  // 新增：用于存储全局中间件
+	router    *router
+	mu        sync.Mutex // 保护 listener、conns、shuttingDown、startedAt、validator、errorHooks、authorizer、methodLimits、responseInterceptor 与 logger 字段
+	listener  net.Listener
+	wg        sync.WaitGroup // 用于追踪活动的连接处理 goroutine
+	validator Validator      // BindAndValidate 使用的校验器
+
+	// errorHooks 在处理链设置了错误时依次调用
+	errorHooks []func(ctx *Context, err *protocol.ErrorObject)
+	// authorizer 在每个请求执行处理链之前集中判断调用方是否有权调用该方法
+	authorizer func(identity interface{}, method string, params json.RawMessage) error
+	// responseInterceptor 在每条响应写出之前检查或修改响应
+	responseInterceptor func(ctx *Context, resp *protocol.Response)
+	// methodLimits 是 SetMethodConcurrency 为方法设置的信号量，容量即并发上限
+	methodLimits map[string]chan struct{}
+
+	conns        map[*serverConn]struct{} // 当前活动的连接
+	shuttingDown bool                     // Close 已被调用
+	builtins     sync.Once                // 保证内置方法只注册一次
+	startedAt    time.Time                // 内置方法注册（即开始提供服务）的时间
+	noHealth     bool                     // 不注册 rpc.health
+
+	logger          Logger
+	tracer          Tracer
+	codec           Codec
+	handshake       func(conn net.Conn) (identity interface{}, err error)
+	errorMapper     func(ctx *Context, err *protocol.ErrorObject) *protocol.ErrorObject
+	maxMessageBytes int64         // 单条请求的最大字节数，0 表示不限制
+	readTimeout     time.Duration // 读取每条请求的期限，0 表示不限制
+	writeTimeout    time.Duration // 写入每条消息的期限，0 表示不限制
+
+	orderedResponses bool // 每个连接上的请求依次处理，响应按请求顺序写回
+	maxInFlight      int  // 每个连接上同时处理的请求数上限，0 表示不限制
+
+	pool *workerPool // 设置了 WithWorkerPool 时所有连接的请求交给它调度
+
+	notificationFallback HandlerFunc // 未注册方法的通知交给它处理
+	useNumber            bool        // Bind 等方法将数字解析为 json.Number
+
+	deadlineFromMeta  bool          // 根据请求 meta 中的 deadline 设置处理器期限
+	deadlineTolerance time.Duration // 容忍的时钟偏差
+
+	versions *versionRouter // 设置了 WithVersionRouter 时按版本分发请求
+
+	tlsConfig *tls.Config // 设置后 Listen 以 TLS 提供服务
+	marshal   MarshalFunc // 替换默认 JSON 编码使用的 json.Marshal，参见 WithMarshal
+
+	acceptBackoffMin time.Duration // Accept 出错后的首次等待时间，0 表示使用默认值
+	acceptBackoffMax time.Duration // Accept 连续出错时的最长等待时间，0 表示使用默认值
 }
 
-func NewServer() *Server {
+// Accept 出错时的默认退避时间，与 net/http 的 Server 相同
+const (
+	defaultAcceptBackoffMin = 5 * time.Millisecond
+	defaultAcceptBackoffMax = time.Second
+)
+
+func NewServer(opts ...ServerOption) *Server {
 	s := &Server{
-		router:            newRouter(),
-		globalMiddlewares: make([]HandlerFunc, 0),
+		router:    newRouter(),
+		validator: requiredValidator{},
+		conns:     make(map[*serverConn]struct{}),
+		logger:    stdLogger{},
+	}
+	s.router.deprecated = func(oldName, newName string) {
+		s.log().Infof("jsonrpc2: method %q is deprecated, use %q instead", oldName, newName)
+	}
+	for _, opt := range opts {
+		opt.applyServer(s)
 	}
 
 	return s
 }
 
+// WithAuthHandshake 设置连接级的鉴权握手。握手函数在连接建立后、读取任何请求之前被调用，
+// 可以直接在 conn 上读写握手数据；返回错误时服务器回写一个 Unauthorized 错误并关闭连接，
+// 成功时返回的 identity 可在该连接的每个请求中通过 ctx.Identity() 读取。
+func WithAuthHandshake(handshake func(conn net.Conn) (identity interface{}, err error)) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.handshake = handshake
+	})
+}
+
+// WithCaseInsensitiveMethods 使方法名匹配不区分大小写：注册与查找时方法名统一转换为小写，
+// 内置方法与别名同样适用，Methods 与 rpc.discover 返回小写形式。默认区分大小写，与规范一致。
+func WithCaseInsensitiveMethods() ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.router.caseInsensitive = true
+	})
+}
+
+// WithOrderedResponses 使每个连接上的请求按到达顺序依次处理，响应严格按请求顺序写回，
+// 适用于假定响应有序的简单客户端。同一连接上的请求不再并发执行，吞吐量会随之下降；不同连接之间仍然并发。
+func WithOrderedResponses() ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.orderedResponses = true
+	})
+}
+
+// WithMaxInFlight 限制每个连接上同时处理的请求数，n <= 0 表示不限制（默认）。
+// 达到上限后新读取的请求排队等待，直到有请求处理完毕；读循环继续读取，$/cancelRequest 通知不占用名额并立即生效，
+// 因此客户端可以取消正在处理的请求来腾出名额。排队的请求也达到 n 个时读循环暂停读取，未读取的数据积压在
+// TCP 缓冲区中，从而对发送过快的客户端形成反压，避免 goroutine 无限堆积。
+func WithMaxInFlight(n int) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.maxInFlight = n
+	})
+}
+
+// WithAcceptBackoff 设置 Accept 返回临时性错误后的退避时间：首次等待 min，之后每次连续出错翻倍，最长为 max。
+// 默认分别为 5ms 与 1s。
+func WithAcceptBackoff(min, max time.Duration) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.acceptBackoffMin = min
+		s.acceptBackoffMax = max
+	})
+}
+
+// WithUseNumber 使 Bind、BindStrict、Params 与 ParamsArray 将数字解析为 json.Number 而不是 float64，
+// 解析到 interface{} 或 map 中的大整数（超过 2^53）因此不会丢失精度。
+func WithUseNumber() ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.useNumber = true
+	})
+}
+
+// WithNotificationFallback 设置未注册方法的通知的处理器，可用于记录未知事件。
+// 按规范，通知无论是否被处理都不会有响应；fallback 单独执行，不经过全局中间件。
+// 通过 HandleDefault 设置了默认处理链时，未注册的通知优先交给默认处理链。
+func WithNotificationFallback(fallback HandlerFunc) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.notificationFallback = fallback
+	})
+}
+
+// WithErrorMapper 设置错误映射函数。处理链设置了错误时，映射函数在写回响应之前被调用，
+// 可用于统一改写错误码、隐藏内部细节等；返回 nil 表示保留原错误。
+func WithErrorMapper(mapper func(ctx *Context, err *protocol.ErrorObject) *protocol.ErrorObject) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.errorMapper = mapper
+	})
+}
+
 // Use 添加一个或多个全局中间件到服务器。
 // 这些中间件将应用于所有已注册的处理器，并在特定于路由的中间件之前执行。
+// Use 可以在服务运行中调用：正在处理的请求保持原有的处理链，之后到达的请求使用新的处理链。
 func (s *Server) Use(middlewares ...HandlerFunc) {
+	s.router.use(middlewares...)
+}
+
+// UseNamespace 为点分命名空间挂载中间件，namespace 下任意深度的方法都会继承这些中间件。
+// 例如挂载在 "account" 与 "account.billing" 上的中间件都会作用于 "account.billing.charge"，
+// 处理链顺序为：全局中间件 → 由浅到深各级命名空间的中间件 → 路由自身的处理链。与 Use 一样可以在运行中调用。
+func (s *Server) UseNamespace(namespace string, middlewares ...HandlerFunc) {
+	s.router.useNamespace(namespace, middlewares...)
+}
+
+// OnError 注册一个在处理链结束后、写回响应之前调用的回调，仅当处理链设置了错误时触发，
+// err 为处理器或中止请求的中间件设置的原始错误（WithErrorMapper 转换之前），可用于上报错误。
+// 通知出错同样会触发；多次调用按注册顺序执行。路由之前产生的错误（如 MethodNotFound）不会触发。
+func (s *Server) OnError(hook func(ctx *Context, err *protocol.ErrorObject)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.errorHooks = append(s.errorHooks, hook)
+}
+
+// SetValidator 设置 ctx.BindAndValidate 使用的校验器。
+// 默认校验器仅支持 `validate:"required"` 标签，传入 nil 则只做解析不做校验。
+func (s *Server) SetValidator(v Validator) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validator = v
+}
+
+// SetAuthorizer 设置集中的鉴权策略，例如基于角色的访问控制。authorize 在每个请求（包括通知）
+// 执行处理链之前调用，identity 为连接鉴权握手（WithAuthHandshake）得到的身份信息。
+// 返回错误时处理链不会执行：*protocol.ErrorObject 原样返回给客户端，其余错误包装为 UnauthorizedError（-32001）。
+// 未注册的方法仍返回 MethodNotFoundError，内置的 ping 也不会调用 authorize；传入 nil 取消鉴权。
+func (s *Server) SetAuthorizer(authorize func(identity interface{}, method string, params json.RawMessage) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.authorizer = authorize
+}
+
+// SetResponseInterceptor 设置在每条响应写出之前调用的拦截器，可以修改 resp，例如在 meta 中附加服务版本、
+// 耗时等信息或删除敏感字段。与 WithErrorMapper 不同，成功响应同样会经过拦截器，且在错误映射之后调用。
+// 处理链之外产生的响应（如 ParseError、MethodNotFound）也会经过拦截器，此时 ctx 为 nil；
+// resp.Meta 可能为 nil，也可能是处理器通过 SetResponseMeta 设置的 map。ctx.Stream 直接写到连接上的流式结果不经过拦截器。传入 nil 取消拦截。
+func (s *Server) SetResponseInterceptor(intercept func(ctx *Context, resp *protocol.Response)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responseInterceptor = intercept
+}
+
+// SetMethodConcurrency 限制 method 在所有连接上同时执行的请求数（包括通知）不超过 n，
+// 适用于生成报表等开销较大的方法，其余方法不受影响。达到上限时新的请求立即得到 -32000 Server busy 错误，
+// 通知被丢弃。通过别名的调用计入目标方法；n <= 0 取消限制。正在执行的请求不受重新设置的影响。
+func (s *Server) SetMethodConcurrency(method string, n int) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.globalMiddlewares = append(s.globalMiddlewares, middlewares...)
+	method = s.router.normalize(method)
+	if n <= 0 {
+		delete(s.methodLimits, method)
+		return
+	}
+	if s.methodLimits == nil {
+		s.methodLimits = make(map[string]chan struct{})
+	}
+	s.methodLimits[method] = make(chan struct{}, n)
 }
 
+// Handle 为方法注册处理链。重复注册同一方法会替换之前的处理链，可用于运行时热更新。
+// 以 rpc. 开头的方法名由规范保留，除替换内置的 rpc.discover 与 rpc.health 外注册这类方法会 panic；
+// 替换内置方法的处理链在 Listen 之前或之后注册均可。
 func (s *Server) Handle(method string, handlers ...HandlerFunc) {
+	if s.router.reserved(method) {
+		panic(fmt.Sprintf("jsonrpc2: method name %q is reserved", method))
+	}
 	s.router.add(method, handlers...)
 }
 
+// HandleDefault 设置未注册方法使用的默认处理链，例如将未知方法转发到其他服务的反向代理。
+// 原始方法名可通过 ctx.Request.Method 读取；默认处理链同样经过全局中间件（命名空间中间件不适用），
+// 请求与通知都会交给它处理。未设置时未注册的方法返回 MethodNotFoundError。
+func (s *Server) HandleDefault(handlers ...HandlerFunc) {
+	s.router.setDefault(handlers...)
+}
+
+// Deregister 注销一个方法，之后对该方法的调用将返回 MethodNotFoundError。
+// 正在执行的请求不受影响。返回值表示该方法此前是否已注册。
+func (s *Server) Deregister(method string) bool {
+	return s.router.remove(method)
+}
+
+// Alias 使对 oldName 的调用分发到 newName 的处理链，用于重命名方法时保持旧名称可用。
+// 每个别名第一次被调用时会记录一条弃用日志。别名可以指向另一个别名，但不能形成环（包括指向自身），
+// 否则返回错误。已注册的同名方法优先于别名。oldName 不能使用保留的 rpc. 前缀。
+func (s *Server) Alias(oldName, newName string) error {
+	if s.router.reserved(oldName) {
+		return fmt.Errorf("jsonrpc2: method name %q is reserved", oldName)
+	}
+	return s.router.addAlias(oldName, newName)
+}
+
+// Methods 返回所有已注册的方法名（按字典序排序），包括内置的 ping、rpc.discover 与 rpc.health。
+func (s *Server) Methods() []string {
+	return s.router.methods()
+}
+
+// Listen 在 TCP 地址 addr 上监听，并在后台 goroutine 中接受连接，监听成功后立即返回。
+// 设置了 WithTLSConfig 时以 TLS 提供服务。
 func (s *Server) Listen(addr string) error {
 	listener, err := net.Listen("tcp", addr)
 	if err != nil {
 		return err
 	}
+	if s.tlsConfig != nil {
+		listener = tls.NewListener(listener, s.tlsConfig)
+	}
+	if err := s.setListener(listener); err != nil {
+		listener.Close()
+		return err
+	}
+	go func() {
+		if err := s.acceptLoop(listener); err != nil {
+			s.log().Errorf("jsonrpc2: accept loop stopped: %v", err)
+		}
+	}()
+	return nil
+}
 
+// Serve 在调用方提供的监听器上接受连接，例如 Unix 域套接字或多路复用的监听器。
+// Serve 会阻塞，直到 Close 关闭监听器后返回 nil，或者 Accept 返回非临时性的错误时返回该错误；
+// 一个 Server 同时只能在一个监听器上提供服务。
+func (s *Server) Serve(l net.Listener) error {
+	if err := s.setListener(l); err != nil {
+		return err
+	}
+	return s.acceptLoop(l)
+}
+
+// setListener 登记服务器使用的监听器并注册内置方法
+func (s *Server) setListener(l net.Listener) error {
 	s.mu.Lock()
-	s.listener = listener
+	if s.shuttingDown {
+		s.mu.Unlock()
+		return errors.New("jsonrpc2: server is shut down")
+	}
+	if s.listener != nil {
+		s.mu.Unlock()
+		return errors.New("jsonrpc2: server is already listening")
+	}
+	s.listener = l
 	s.mu.Unlock()
 
-	s.Handle("ping", func(ctx *Context) {
-		ctx.Result("pong")
+	s.registerBuiltins()
+	return nil
+}
+
+// ServeConn 在调用方提供的连接上提供服务，阻塞直至连接断开或服务器关闭，适用于 net.Pipe、
+// 已完成协议升级的连接等不经过 Listen 的场景。服务器已关闭时直接关闭 conn 并返回。
+func (s *Server) ServeConn(conn net.Conn) {
+	s.registerBuiltins()
+	sc := newServerConn(conn, s.codec, s.marshal, s.writeTimeout)
+	if !s.trackConn(sc) {
+		conn.Close()
+		return
+	}
+	s.handleConnection(sc)
+}
+
+// HealthStatus 是内置方法 rpc.health 的返回值
+type HealthStatus struct {
+	Status      string  `json:"status"`
+	Connections int     `json:"connections"` // 当前活动的连接数
+	Methods     int     `json:"methods"`     // 已注册的方法数
+	Uptime      float64 `json:"uptime"`      // 开始提供服务以来的秒数
+}
+
+// WithoutHealth 使服务器不自动注册内置的 rpc.health 方法。
+func WithoutHealth() ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.noHealth = true
 	})
+}
 
-	go s.acceptLoop()
-	return nil
+// ConnectionCount 返回当前活动的连接数。
+func (s *Server) ConnectionCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.conns)
 }
 
-func (s *Server) acceptLoop() {
+// reservedPrefix 是规范保留给内部方法的方法名前缀
+const reservedPrefix = "rpc."
+
+// builtinMethods 是服务器内置的以 rpc. 开头的方法，只有它们可以用 Handle 注册
+var builtinMethods = map[string]bool{
+	"rpc.discover": true,
+	"rpc.health":   true,
+}
+
+// registerBuiltins 注册内置的 ping、rpc.discover 与 rpc.health 方法
+func (s *Server) registerBuiltins() {
+	s.builtins.Do(func() {
+		s.mu.Lock()
+		s.startedAt = time.Now()
+		s.mu.Unlock()
+		// 内置的 ping 是保活探测，不经过中间件与鉴权，避免全局鉴权中间件拒绝健康检查；
+		// 用 Handle 注册自定义的 ping（在 Listen 之前或之后均可）会替换它，自定义的处理链照常经过中间件
+		s.router.addBare("ping", func(ctx *Context) {
+			ctx.Result("pong")
+		})
+		// 内置方法在第一次提供服务时才注册，此前用 Handle 注册的同名方法保持不变
+		s.router.addDefault("rpc.discover", func(ctx *Context) {
+			ctx.Result(s.Methods())
+		})
+		if !s.noHealth {
+			s.router.addDefault("rpc.health", func(ctx *Context) {
+				ctx.Result(s.health())
+			})
+		}
+	})
+}
+
+// health 汇总服务器当前的运行状态
+func (s *Server) health() HealthStatus {
+	s.mu.Lock()
+	conns := len(s.conns)
+	startedAt := s.startedAt
+	s.mu.Unlock()
+	return HealthStatus{
+		Status:      "ok",
+		Connections: conns,
+		Methods:     len(s.Methods()),
+		Uptime:      time.Since(startedAt).Seconds(),
+	}
+}
+
+// acceptLoop 持续接受连接直到监听器关闭，此时返回 nil。Accept 返回超时或临时性错误（例如文件描述符耗尽）时
+// 按指数退避重试，避免空转占满 CPU，成功接受连接后退避时间复位；其他错误使 acceptLoop 停止并返回该错误。
+func (s *Server) acceptLoop(l net.Listener) error {
+	minDelay, maxDelay := s.acceptBackoffMin, s.acceptBackoffMax
+	if minDelay <= 0 {
+		minDelay = defaultAcceptBackoffMin
+	}
+	if maxDelay <= 0 {
+		maxDelay = defaultAcceptBackoffMax
+	}
+	var delay time.Duration
 	for {
-		conn, err := s.listener.Accept()
+		conn, err := l.Accept()
 		if err != nil {
 			if errors.Is(err, net.ErrClosed) {
-				log.Println("jsonrpc2: listener closed, shutting down accept loop.")
-				return
+				s.log().Infof("jsonrpc2: listener closed, shutting down accept loop.")
+				return nil
+			}
+			if !isTemporaryAcceptError(err) {
+				return err
 			}
-			log.Printf("jsonrpc2: failed to accept connection: %v", err)
+			if delay == 0 {
+				delay = minDelay
+			} else if delay *= 2; delay > maxDelay {
+				delay = maxDelay
+			}
+			s.log().Errorf("jsonrpc2: failed to accept connection: %v; retrying in %v", err, delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		sc := newServerConn(conn, s.codec, s.marshal, s.writeTimeout)
+		if !s.trackConn(sc) {
+			conn.Close()
 			continue
 		}
-		s.wg.Add(1)
-		go s.handleConnection(conn)
+		go s.handleConnection(sc)
+	}
+}
+
+// isTemporaryAcceptError 报告 Accept 返回的错误是否为超时或临时性错误，与 net/http 一样只重试这类错误
+func isTemporaryAcceptError(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	var temp interface{ Temporary() bool }
+	return errors.As(err, &temp) && temp.Temporary()
+}
+
+// trackConn 登记一个新连接；服务器正在关闭时返回 false。
+// wg.Add 与 shuttingDown 的检查在同一把锁内完成，保证 Close 中的 wg.Wait 不会与之竞争。
+func (s *Server) trackConn(sc *serverConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.shuttingDown {
+		return false
 	}
+	s.conns[sc] = struct{}{}
+	s.wg.Add(1)
+	return true
+}
+
+func (s *Server) untrackConn(sc *serverConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.conns, sc)
+}
+
+func (s *Server) isShuttingDown() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.shuttingDown
 }
 
+// Close 优雅地关闭服务器：
+//  1. 关闭监听器，停止接受新连接；
+//  2. 停止从现有连接读取新请求；
+//  3. 在 ctx 的期限内等待正在处理的请求完成并写回响应，每个连接在其请求全部完成后关闭，
+//     客户端因此总是先收到响应再读到连接关闭；
+//  4. 期限到达时强制关闭所有剩余连接并返回 ctx.Err()。
 func (s *Server) Close(ctx context.Context) error {
 	s.mu.Lock()
 	listener := s.listener
+	if listener == nil && len(s.conns) == 0 {
+		s.mu.Unlock()
+		return errors.New("jsonrpc2: server not started")
+	}
+	s.shuttingDown = true
+	conns := make([]*serverConn, 0, len(s.conns))
+	for sc := range s.conns {
+		conns = append(conns, sc)
+	}
 	s.mu.Unlock()
 
-	if listener == nil {
-		return errors.New("jsonrpc2: server not started")
+	var err error
+	if listener != nil {
+		err = listener.Close()
 	}
 
-	err := listener.Close()
+	// 让阻塞在读取上的连接立即返回，读循环会据此退出，而连接保持打开以写回响应
+	for _, sc := range conns {
+		sc.stopReading()
+	}
 
 	done := make(chan struct{})
 	go func() {
@@ -92,79 +505,443 @@ func (s *Server) Close(ctx context.Context) error {
 		close(done)
 	}()
 
+	// 工作池处理完已排队的请求后退出
+	if s.pool != nil {
+		defer s.pool.stop()
+	}
 	select {
 	case <-done:
 		return err
 	case <-ctx.Done():
+		s.mu.Lock()
+		for sc := range s.conns {
+			sc.close()
+		}
+		s.mu.Unlock()
 		return ctx.Err()
 	}
 }
 
-func (s *Server) handleConnection(conn net.Conn) {
+// Broadcast 向当前所有活动连接推送一条通知，返回成功写入的连接数。
+// 通知与响应共用每个连接的写锁，不会与正在写出的响应交错。
+func (s *Server) Broadcast(method string, params interface{}) int {
+	raw, err := marshalValue(s.marshal, params)
+	if err != nil {
+		s.log().Errorf("jsonrpc2: failed to marshal broadcast params: %v", err)
+		return 0
+	}
+
+	s.mu.Lock()
+	conns := make([]*serverConn, 0, len(s.conns))
+	for sc := range s.conns {
+		conns = append(conns, sc)
+	}
+	s.mu.Unlock()
+
+	sent := 0
+	for _, sc := range conns {
+		if err := sc.notify(method, raw); err != nil {
+			s.log().Errorf("jsonrpc2: failed to write broadcast: %v", err)
+			continue
+		}
+		sent++
+	}
+	return sent
+}
+
+// handleConnection 读取连接上的请求直至连接断开或服务器关闭，
+// 之后等待该连接上的请求全部处理完毕再关闭连接。
+func (s *Server) handleConnection(sc *serverConn) {
 	defer s.wg.Done()
-	defer conn.Close()
 
-	decoder := json.NewDecoder(conn)
-	encoder := json.NewEncoder(conn)
-	var sendMutex sync.Mutex
+	if sc.transport != nil {
+		s.readTransport(sc)
+		sc.requests.Wait()
+		sc.close()
+		s.untrackConn(sc)
+		return
+	}
+
+	// TLS 握手与鉴权握手同样受读取期限约束
+	if s.readTimeout > 0 {
+		sc.conn.SetReadDeadline(time.Now().Add(s.readTimeout))
+	}
+	if err := tlsHandshake(sc.conn); err != nil {
+		if !s.isShuttingDown() {
+			s.log().Infof("jsonrpc2: TLS handshake failed for %v: %v", sc.conn.RemoteAddr(), err)
+		}
+		sc.close()
+		s.untrackConn(sc)
+		return
+	}
+
+	if s.handshake != nil {
+		identity, err := s.handshake(sc.conn)
+		if err != nil && s.isShuttingDown() {
+			// 握手被 Close 中断，直接关闭连接
+			sc.close()
+			s.untrackConn(sc)
+			return
+		}
+		if err != nil {
+			s.log().Infof("jsonrpc2: handshake failed for %v: %v", sc.conn.RemoteAddr(), err)
+			s.writeResponse(sc, nil, protocol.UnauthorizedError(err.Error()))
+			sc.close()
+			s.untrackConn(sc)
+			return
+		}
+		sc.identity = identity
+	}
+
+	s.readRequests(sc)
+	sc.requests.Wait()
+	sc.close()
+	s.untrackConn(sc)
+}
+
+func (s *Server) readRequests(sc *serverConn) {
+	decoder := newStreamDecoder(sc.conn, s.maxMessageBytes, s.codec)
 
 	for {
+		if s.readTimeout > 0 {
+			// 每条消息刷新一次期限；先设置期限再检查关闭状态，避免覆盖 Close 设置的立即超时
+			sc.conn.SetReadDeadline(time.Now().Add(s.readTimeout))
+		}
+		// Close 之后不再接受新的请求，包括解码器中已经缓冲但尚未解析的请求；
+		// 已分发的请求照常处理完毕并写回响应后，handleConnection 才会关闭连接
+		if s.isShuttingDown() {
+			return
+		}
 		var req protocol.Request
-		if err := decoder.Decode(&req); err != nil {
-			if err != io.EOF {
-				s.writeResponse(encoder, &sendMutex, nil, protocol.ParseError(err.Error()))
+		raw, err := s.decodeRequest(decoder, &req)
+		if err != nil {
+			if s.isShuttingDown() {
+				// 读取被 Close 中断，不再回写错误
+				return
+			}
+			var netErr net.Error
+			if errors.As(err, &netErr) && netErr.Timeout() {
+				s.log().Infof("jsonrpc2: read timeout on %v, closing connection", sc.conn.RemoteAddr())
+				return
+			}
+			var syntaxErr *json.SyntaxError
+			if errors.As(err, &syntaxErr) {
+				// 尽量带上损坏消息中可识别的 id；对于按行分隔的消息，跳过损坏的这一行后继续读取后续请求
+				s.writeResponse(sc, decoder.recoverID(), protocol.ParseError(err.Error()))
+				if decoder.resync() != nil {
+					return
+				}
+				continue
+			}
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) && s.codec == nil {
+				// 消息是完整的 JSON 值但不是合法的请求对象（例如 method 不是字符串），
+				// 解码器已越过这个值，后续的消息（包括没有分隔符、紧接着的对象）可以继续解析
+				var id interface{}
+				if len(req.ID) > 0 && validID(req.ID) {
+					id = req.ID
+				}
+				s.writeResponse(sc, id, protocol.InvalidRequestError(strings.TrimPrefix(err.Error(), "json: ")))
+				continue
+			}
+			if errors.Is(err, ErrMessageTooLarge) {
+				s.writeResponse(sc, nil, protocol.InvalidRequestError(err.Error()))
+			} else if err != io.EOF {
+				s.writeResponse(sc, nil, protocol.ParseError(err.Error()))
 			}
 			return
 		}
-		s.wg.Add(1)
-		go func() {
-			defer s.wg.Done()
-			s.handleRequest(encoder, &sendMutex, conn, &req)
-		}()
+		s.dispatch(sc, &req, raw)
+	}
+}
+
+// decodeRequest 解码下一条请求到 req 中并返回它的原始字节。未设置 Codec 时先整条读出原始 JSON 再解析，
+// 以便 ctx.RawRequest 取得报文原文；设置了 Codec 时直接解码，raw 为 nil。
+func (s *Server) decodeRequest(decoder *streamDecoder, req *protocol.Request) (raw json.RawMessage, err error) {
+	if s.codec != nil {
+		return nil, decoder.Decode(req)
+	}
+	if err := decoder.Decode(&raw); err != nil {
+		return nil, err
+	}
+	return raw, json.Unmarshal(raw, req)
+}
+
+// dispatch 在独立的 goroutine 中处理一条请求，只在读循环中调用。
+// 设置了 WithOrderedResponses 时每个请求等待前一个请求处理完毕后才开始，读循环本身不会被阻塞。
+func (s *Server) dispatch(sc *serverConn, req *protocol.Request, raw []byte) {
+	// 取消通知在读循环中立即生效，即使请求是依次处理的；之后照常分发，注册了同名处理器时仍会被调用
+	cancelNotification := req.Method == cancelRequestMethod && len(req.ID) == 0
+	if cancelNotification {
+		var params struct {
+			ID json.RawMessage `json:"id"`
+		}
+		if json.Unmarshal(req.Params, &params) == nil && len(params.ID) > 0 {
+			sc.cancelRequest(params.ID)
+		}
+	}
+
+	// 请求在开始排队时即登记，排队中的请求同样可以被取消
+	base, cancel := context.WithCancel(context.Background())
+	untrack := func() {}
+	if len(req.ID) > 0 {
+		untrack = sc.trackRequest(req.ID, cancel)
+	}
+
+	var prev <-chan struct{}
+	var done chan struct{}
+	if s.orderedResponses {
+		prev = sc.lastDone
+		done = make(chan struct{})
+		sc.lastDone = done
+	}
+
+	// 取消通知不占用处理名额，名额耗尽时也能被读取并立即生效
+	limited := s.maxInFlight > 0 && !cancelNotification
+
+	sc.requests.Add(1)
+	run := func(handle func()) {
+		defer sc.requests.Done()
+		if limited {
+			defer func() { <-sc.slots }()
+		}
+		defer cancel()
+		defer untrack()
+		if prev != nil {
+			<-prev
+		}
+		handle()
+		if done != nil {
+			close(done)
+		}
+	}
+	handle := func() { s.handleRequest(base, sc, req, raw) }
+	start := func() {
+		switch {
+		case s.pool == nil:
+			go run(handle)
+		case !s.pool.submit(sc, func() { run(handle) }):
+			// 工作池队列已满：不占用工作协程，在有序模式下同样按顺序写回繁忙错误
+			go run(func() {
+				if len(req.ID) > 0 {
+					s.writeResponse(sc, req.ID, errServerBusy())
+				}
+			})
+		}
+	}
+	if !limited {
+		start()
+		return
+	}
+
+	if sc.slots == nil {
+		sc.slots = make(chan struct{}, s.maxInFlight)
+		sc.queued = make(chan struct{}, s.maxInFlight)
 	}
+	// 名额耗尽时请求在独立的 goroutine 中等待，读循环继续读取；等待的请求也达到上限时读循环才暂停
+	sc.queued <- struct{}{}
+	go func() {
+		// 有序模式下先等前一个请求处理完毕再占用名额，避免后面的请求占满名额而前一个请求无法开始
+		if prev != nil {
+			<-prev
+		}
+		sc.slots <- struct{}{}
+		<-sc.queued
+		start()
+	}()
 }
 
-func (s *Server) handleRequest(encoder *json.Encoder, sendMutex *sync.Mutex, conn net.Conn, req *protocol.Request) {
+// handleRequest 处理一条请求，base 是请求 context 的基础，客户端取消请求时 base 会被取消。
+// raw 是请求的原始字节，无法取得时为 nil。
+func (s *Server) handleRequest(base context.Context, sc *serverConn, req *protocol.Request, raw []byte) {
+	// 没有 id 的请求是通知：照常执行处理链，但不返回任何响应
+	notification := len(req.ID) == 0
+	if !notification && !validID(req.ID) {
+		s.writeResponse(sc, nil, protocol.InvalidRequestError("id must be a string, number or null"))
+		return
+	}
+	// 缺少 method 的请求不合法，而不是方法不存在
+	if strings.TrimSpace(req.Method) == "" {
+		if !notification {
+			s.writeResponse(sc, req.ID, protocol.InvalidRequestError("method is required"))
+		}
+		return
+	}
 
-	if req.ID == nil {
-		s.writeResponse(encoder, sendMutex, nil, protocol.ParseError(req.ID))
+	// method 是实际分发到的方法名，按版本分发时带有版本前缀，req.Method 保持请求中的原值
+	method := req.Method
+	if s.versions != nil {
+		var err *protocol.ErrorObject
+		if method, err = s.resolveVersion(req); err != nil {
+			if !notification {
+				s.writeResponse(sc, req.ID, err)
+			}
+			return
+		}
+	}
+
+	var chain []HandlerFunc
+	bare := false
+	if entry, found := s.router.find(method); found {
+		chain = entry.combined
+		bare = entry.bare
+	} else if strings.HasPrefix(s.router.normalize(method), reservedPrefix) {
+		// 未定义的保留方法不交给默认处理链或通知兜底，直接拒绝
+		if !notification {
+			s.writeResponse(sc, req.ID, protocol.MethodNotFoundError(fmt.Sprintf("%s is a reserved method name", req.Method)))
+		}
+		return
+	} else if entry := s.router.defaultEntry(); entry != nil {
+		chain = entry.combined
+	} else if notification && s.notificationFallback != nil {
+		chain = []HandlerFunc{s.notificationFallback}
+	} else {
+		if !notification {
+			s.writeResponse(sc, req.ID, protocol.MethodNotFoundError(req.Method))
+		}
 		return
 	}
-	entry, found := s.router.find(req.Method)
-	if !found {
-		s.writeResponse(encoder, sendMutex, req.ID, protocol.MethodNotFoundError(req.Method))
+
+	base, cancel, deadlineErr := s.applyDeadline(base, req)
+	defer cancel()
+	if deadlineErr != nil {
+		if !notification {
+			s.writeResponse(sc, req.ID, deadlineErr)
+		}
 		return
 	}
 
 	s.mu.Lock()
-	finalChain := make([]HandlerFunc, 0, len(s.globalMiddlewares)+len(entry.chain))
-	// 1. 添加全局中间件
-	finalChain = append(finalChain, s.globalMiddlewares...)
+	validator := s.validator
+	errorHooks := s.errorHooks
+	authorizer := s.authorizer
+	limited := len(s.methodLimits) > 0
 	s.mu.Unlock()
-	// 2. 添加特定于路由的中间件和处理器
-	finalChain = append(finalChain, entry.chain...)
 
-	ctx := &Context{
-		Context:      context.Background(),
-		Conn:         conn,
-		Request:      req,
-		handlerChain: finalChain,
-		handlerIdx:   -1,
+	if authorizer != nil && !bare {
+		if err := authorizer(sc.identity, method, req.Params); err != nil {
+			if !notification {
+				rpcErr, ok := AsRPCError(err)
+				if !ok {
+					rpcErr = protocol.UnauthorizedError(err.Error())
+				}
+				s.writeResponse(sc, req.ID, rpcErr)
+			}
+			return
+		}
 	}
+
+	if limited {
+		release, ok := s.acquireMethod(method)
+		if !ok {
+			if !notification {
+				s.writeResponse(sc, req.ID, errServerBusy())
+			}
+			return
+		}
+		defer release()
+	}
+
+	// chain 已按 全局中间件 → 路由中间件 → 处理器 的顺序组合好
+	ctx := acquireContext()
+	defer releaseContext(ctx)
+	// 客户端可通过 $/cancelRequest 通知取消该请求，处理器通过 ctx.Done() 感知
+	ctx.Context = base
+	if id := decodeID(req.ID); id != nil {
+		ctx.Context = context.WithValue(ctx.Context, requestIDContextKey{}, id)
+	}
+	var endSpan func(err *protocol.ErrorObject)
+	if s.tracer != nil {
+		ctx.Context, endSpan = s.tracer(ContextWithMeta(ctx.Context, req.Meta), req.Method)
+	}
+	ctx.Conn = sc.conn
+	ctx.sconn = sc
+	ctx.server = s
+	ctx.identity = sc.identity
+	ctx.Request = req
+	ctx.rawRequest = raw
+	ctx.handlerChain = chain
+	ctx.validator = validator
+	ctx.useNumber = s.useNumber
+
 	ctx.Next()
+	if endSpan != nil {
+		endSpan(ctx.responseError)
+	}
 	if ctx.responseError != nil {
-		s.writeResponse(encoder, sendMutex, req.ID, ctx.responseError)
+		for _, hook := range errorHooks {
+			hook(ctx, ctx.responseError)
+		}
+	}
+	if notification {
+		ctx.runAfterResponse(nil)
+		return
+	}
+	// 响应只能包含 result 或 error 之一：处理器同时设置二者时错误优先
+	var writeErr error
+	if ctx.responseError != nil {
+		respErr := ctx.responseError
+		if s.errorMapper != nil {
+			if mapped := s.errorMapper(ctx, respErr); mapped != nil {
+				respErr = mapped
+			}
+		}
+		writeErr = s.writeResponseMeta(ctx, sc, req.ID, respErr, ctx.responseMeta)
+	} else if stream, ok := ctx.responseResult.(streamResult); ok {
+		writeErr = s.writeStream(sc, req.ID, stream)
 	} else {
-		s.writeResponse(encoder, sendMutex, req.ID, ctx.responseResult)
+		writeErr = s.writeResponseMeta(ctx, sc, req.ID, ctx.responseResult, ctx.responseMeta)
+	}
+	ctx.runAfterResponse(writeErr)
+}
+
+// acquireMethod 占用 method 的一个并发名额，返回释放函数；名额已满时返回 false
+func (s *Server) acquireMethod(method string) (release func(), ok bool) {
+	method = s.router.resolve(method)
+	s.mu.Lock()
+	sem := s.methodLimits[method]
+	s.mu.Unlock()
+	if sem == nil {
+		return func() {}, true
+	}
+	select {
+	case sem <- struct{}{}:
+		return func() { <-sem }, true
+	default:
+		return nil, false
+	}
+}
+
+func (s *Server) writeResponse(sc *serverConn, id interface{}, data interface{}) error {
+	return s.writeResponseMeta(nil, sc, id, data, nil)
+}
+
+// writeResponseMeta 写回一个携带响应元数据的响应，meta 为空时与 writeResponse 相同。
+// ctx 是产生该响应的请求上下文，会传给 SetResponseInterceptor 设置的拦截器，处理链之外产生的响应（如解析错误）为 nil。
+// 写入失败时记录日志并返回错误。
+func (s *Server) writeResponseMeta(ctx *Context, sc *serverConn, id interface{}, data interface{}, meta map[string]string) error {
+	resp := createResponse(id, data)
+	resp.Meta = meta
+	s.mu.Lock()
+	intercept := s.responseInterceptor
+	s.mu.Unlock()
+	if intercept != nil {
+		intercept(ctx, &resp)
+	}
+	err := sc.write(resp)
+	if err != nil {
+		s.log().Errorf("jsonrpc2: failed to write response: %v", err)
 	}
+	return err
 }
 
-func (s *Server) writeResponse(encoder *json.Encoder, m *sync.Mutex, id interface{}, data interface{}) {
-	m.Lock()
-	defer m.Unlock()
-	if err := encoder.Encode(createResponse(id, data)); err != nil {
-		log.Printf("jsonrpc2: failed to write response: %v", err)
+// validID 报告原始 id 是否为规范允许的字符串、数字或 null。
+func validID(id json.RawMessage) bool {
+	switch c := id[0]; {
+	case c == '"', c == 'n', c == '-', c >= '0' && c <= '9':
+		return true
 	}
+	return false
 }
 
 // createResponse 是一个辅助函数，用于构建响应对象
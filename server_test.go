@@ -0,0 +1,733 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestMaxInFlightPausesAndResumes(t *testing.T) {
+	s := NewServer(WithMaxInFlight(2))
+	var mu sync.Mutex
+	started := 0
+	release := make(chan struct{})
+	s.Handle("block", func(ctx *Context) {
+		mu.Lock()
+		started++
+		mu.Unlock()
+		<-release
+		ctx.Result(1)
+	})
+	c := startServer(t, s)
+	calls := make([]*Call, 5)
+	for i := range calls {
+		calls[i] = c.Go("block", nil, nil, make(chan *Call, 1))
+	}
+	count := func() int {
+		time.Sleep(50 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		return started
+	}
+	if n := count(); n != 2 {
+		t.Fatalf("started %d handlers, want 2", n)
+	}
+	release <- struct{}{}
+	if n := count(); n != 3 {
+		t.Fatalf("started %d handlers after one finished, want 3", n)
+	}
+	close(release)
+	for _, call := range calls {
+		if r := <-call.Done; r.Error != nil {
+			t.Fatal(r.Error)
+		}
+	}
+}
+
+func TestMaxInFlightCancelWhileSaturated(t *testing.T) {
+	s := NewServer(WithMaxInFlight(1))
+	s.Handle("wait", func(ctx *Context) {
+		<-ctx.Done()
+		ctx.Result("cancelled")
+	})
+	s.Handle("x", func(ctx *Context) { ctx.Result(7) })
+	c := startServer(t, s)
+
+	blocked := c.Go("wait", nil, nil, make(chan *Call, 1))
+	queued := c.Go("x", nil, nil, make(chan *Call, 1))
+	time.Sleep(20 * time.Millisecond)
+	// 名额被占满时取消通知仍被读取，正在处理的请求结束后排队的请求得以执行
+	if !c.Cancel(blocked.ID) {
+		t.Fatal("Cancel returned false")
+	}
+	select {
+	case r := <-queued.Done:
+		if r.Error != nil {
+			t.Fatal(r.Error)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("queued request did not run after the in-flight request was cancelled")
+	}
+}
+
+// tempError 是一个临时性的 Accept 错误，例如文件描述符耗尽
+type tempError struct{}
+
+func (tempError) Error() string   { return "accept: too many open files" }
+func (tempError) Timeout() bool   { return false }
+func (tempError) Temporary() bool { return true }
+
+// flakyListener 在前 fails 次 Accept 时返回 err，并记录每次 Accept 的时间
+type flakyListener struct {
+	net.Listener
+	err   error
+	mu    sync.Mutex
+	fails int
+	times []time.Time
+}
+
+func (l *flakyListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	l.times = append(l.times, time.Now())
+	fail := l.fails > 0
+	l.fails--
+	l.mu.Unlock()
+	if fail {
+		return nil, l.err
+	}
+	return l.Listener.Accept()
+}
+
+func TestAcceptBackoffOnTemporaryErrors(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fl := &flakyListener{Listener: inner, err: tempError{}, fails: 6}
+	s := NewServer(WithAcceptBackoff(10*time.Millisecond, 40*time.Millisecond))
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(fl) }()
+	c, err := Dial(inner.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.Ping() {
+		t.Fatal("ping failed")
+	}
+	c.Close()
+	fl.mu.Lock()
+	times := fl.times
+	fl.mu.Unlock()
+	want := []time.Duration{10, 20, 40, 40, 40, 40}
+	for i, w := range want {
+		if gap := times[i+1].Sub(times[i]); gap < w*time.Millisecond {
+			t.Fatalf("retry %d after %v, want at least %v", i, gap, w*time.Millisecond)
+		}
+	}
+	s.Close(context.Background())
+	if err := <-done; err != nil {
+		t.Fatalf("Serve = %v after Close, want nil", err)
+	}
+}
+
+func TestServeReturnsPermanentAcceptError(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer inner.Close()
+	permanent := errors.New("accept: listener broken")
+	fl := &flakyListener{Listener: inner, err: permanent, fails: 1}
+	s := NewServer()
+	done := make(chan error, 1)
+	go func() { done <- s.Serve(fl) }()
+	select {
+	case err := <-done:
+		if err != permanent {
+			t.Fatalf("Serve = %v, want %v", err, permanent)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Serve kept retrying a permanent error")
+	}
+}
+
+func TestResponseInterceptor(t *testing.T) {
+	s := NewServer()
+	s.Handle("ok", func(ctx *Context) {
+		ctx.SetResponseMeta("a", "1")
+		ctx.Result(1)
+	})
+	s.Handle("bad", func(ctx *Context) { ctx.Error(protocol.NewError(1, "secret", "data")) })
+	var nilCtx int32
+	s.SetResponseInterceptor(func(ctx *Context, resp *protocol.Response) {
+		if ctx == nil {
+			atomic.AddInt32(&nilCtx, 1)
+		}
+		if resp.Meta == nil {
+			resp.Meta = map[string]string{}
+		}
+		resp.Meta["version"] = "v1"
+		if resp.Error != nil {
+			resp.Error.Data = nil
+		}
+	})
+	c := startServer(t, s)
+	for _, m := range []string{"ok", "bad", "missing"} {
+		cm := &CallMeta{}
+		err := c.CallContext(ContextWithCallMeta(context.Background(), cm), m, nil, nil)
+		if cm.Meta["version"] != "v1" {
+			t.Fatalf("%s: response meta %v (err %v)", m, cm.Meta, err)
+		}
+		if e, ok := AsRPCError(err); ok && e.Data != nil && m == "bad" {
+			t.Fatalf("error data was not stripped: %v", e)
+		}
+	}
+	// 未注册方法的错误在处理链之外产生，拦截器收到的 ctx 为 nil
+	if n := atomic.LoadInt32(&nilCtx); n != 1 {
+		t.Fatalf("interceptor saw %d nil contexts, want 1", n)
+	}
+}
+
+func TestCloseDrainsInFlightRequests(t *testing.T) {
+	s := NewServer()
+	s.Handle("slow", func(ctx *Context) {
+		time.Sleep(500 * time.Millisecond)
+		ctx.Result(1)
+	})
+	s.Handle("fast", func(ctx *Context) {
+		time.Sleep(50 * time.Millisecond)
+		ctx.Result(1)
+	})
+	c := startServer(t, s)
+	c2, err := Dial(s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+	slow := c.Go("slow", nil, nil, nil)
+	fast := c2.Go("fast", nil, nil, nil)
+	time.Sleep(10 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := s.Close(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("Close = %v, want DeadlineExceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Fatalf("Close returned after %v", elapsed)
+	}
+	// 期限内完成的请求照常写回响应
+	if r := <-fast.Done; r.Error != nil {
+		t.Fatal(r.Error)
+	}
+	// 期限到达后剩余的连接被强制关闭
+	select {
+	case r := <-slow.Done:
+		if r.Error == nil {
+			t.Fatal("slow call succeeded after its connection was force closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("connection was not force closed")
+	}
+}
+
+func TestResponseHasResultOrError(t *testing.T) {
+	s := NewServer()
+	s.Handle("both", func(ctx *Context) {
+		ctx.Result(1)
+		ctx.Error(protocol.InternalError(nil))
+	})
+	s.Handle("null", func(ctx *Context) {})
+	startServer(t, s)
+	rc := dialRaw(t, s)
+
+	rc.send(`{"jsonrpc":"2.0","method":"both","id":1}`)
+	resp := rc.read()
+	if _, ok := resp["result"]; ok || resp["error"] == nil {
+		t.Fatalf("got %v, want only an error", resp)
+	}
+	rc.send(`{"jsonrpc":"2.0","method":"null","id":2}`)
+	resp = rc.read()
+	if r, ok := resp["result"]; !ok || r != nil || resp["error"] != nil {
+		t.Fatalf("got %v, want a null result", resp)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	s := NewServer()
+	startServer(t, s).Close()
+	waitConns := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(time.Second)
+		for s.ConnectionCount() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("server has %d connections, want %d", s.ConnectionCount(), n)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitConns(0)
+	conns := []*rawConn{dialRaw(t, s), dialRaw(t, s)}
+	waitConns(2)
+	if n := s.Broadcast("ev", 1); n != 2 {
+		t.Fatalf("Broadcast reached %d connections, want 2", n)
+	}
+	for i, rc := range conns {
+		if msg := rc.read(); msg["method"] != "ev" || msg["params"] != float64(1) || msg["id"] != nil {
+			t.Fatalf("connection %d got %v", i, msg)
+		}
+	}
+}
+
+func TestAuthHandshake(t *testing.T) {
+	s := NewServer(WithAuthHandshake(func(conn net.Conn) (interface{}, error) {
+		b := make([]byte, 4)
+		if _, err := io.ReadFull(conn, b); err != nil || string(b) != "tok\n" {
+			return nil, errors.New("bad token")
+		}
+		return "alice", nil
+	}))
+	s.Handle("who", func(ctx *Context) { ctx.Result(ctx.Identity()) })
+	startServer(t, s)
+
+	rc := dialRaw(t, s)
+	rc.send("tok\n{\"jsonrpc\":\"2.0\",\"method\":\"who\",\"id\":1}\n")
+	if resp := rc.read(); resp["result"] != "alice" {
+		t.Fatalf("got %v", resp)
+	}
+
+	// 握手失败时返回 Unauthorized 错误并关闭连接
+	bad := dialRaw(t, s)
+	bad.send("bad\n")
+	if resp := bad.read(); errorCode(resp) != protocol.CodeUnauthorized {
+		t.Fatalf("got %v, want Unauthorized", resp)
+	}
+	var resp map[string]interface{}
+	if err := bad.dec.Decode(&resp); err != io.EOF {
+		t.Fatalf("connection still open after a failed handshake: %v", err)
+	}
+}
+
+func TestResponseEchoesOriginalID(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	startServer(t, s)
+	rc := dialRaw(t, s)
+	// 数字 id 按原文写回，不会因经过 float64 而丢失精度或改变写法
+	for _, id := range []string{`42`, `"abc"`, `12345678901234567890123`, `1e3`, `null`} {
+		rc.send(fmt.Sprintf(`{"jsonrpc":"2.0","method":"x","id":%s}`+"\n", id))
+		rc.conn.SetReadDeadline(time.Now().Add(time.Second))
+		var raw json.RawMessage
+		if err := rc.dec.Decode(&raw); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(raw), `"id":`+id+`}`) {
+			t.Fatalf("id %s: response %s", id, raw)
+		}
+	}
+	// 不合法的 id 得到 id 为 null 的 InvalidRequest
+	rc.send(`{"jsonrpc":"2.0","method":"x","id":{}}` + "\n")
+	if resp := rc.read(); errorCode(resp) != protocol.CodeInvalidRequest || resp["id"] != nil {
+		t.Fatalf("got %v", resp)
+	}
+}
+
+func TestErrorMapper(t *testing.T) {
+	var orig string
+	s := NewServer(WithErrorMapper(func(ctx *Context, err *protocol.ErrorObject) *protocol.ErrorObject {
+		if err.Code == protocol.CodeInternalError {
+			orig = fmt.Sprint(err.Data)
+			return &protocol.ErrorObject{Code: err.Code, Message: "internal error"}
+		}
+		return nil
+	}))
+	s.Handle("x", func(ctx *Context) { ctx.Error(protocol.InternalError("db password wrong")) })
+	s.Handle("y", func(ctx *Context) { ctx.Error(protocol.InvalidParamsError("bad")) })
+	c := startServer(t, s)
+
+	e, _ := AsRPCError(c.Call("x", nil, nil, 5))
+	if e == nil || e.Message != "internal error" || e.Data != nil {
+		t.Fatalf("got %+v, want the mapped error", e)
+	}
+	if orig != "db password wrong" {
+		t.Fatalf("mapper saw data %q", orig)
+	}
+	// 返回 nil 时保留原错误
+	e, _ = AsRPCError(c.Call("y", nil, nil, 5))
+	if e == nil || e.Code != protocol.CodeInvalidParams {
+		t.Fatalf("got %+v, want the original error", e)
+	}
+}
+
+func TestOrderedResponses(t *testing.T) {
+	s := NewServer(WithOrderedResponses())
+	s.Handle("sleep", func(ctx *Context) {
+		var ms int
+		ctx.Bind(&ms)
+		time.Sleep(time.Duration(ms) * time.Millisecond)
+		ctx.Result(ms)
+	})
+	startServer(t, s)
+	rc := dialRaw(t, s)
+	durs := []int{50, 10, 30, 0, 20}
+	for i, d := range durs {
+		rc.send(fmt.Sprintf(`{"jsonrpc":"2.0","method":"sleep","params":%d,"id":%d}`, d, i))
+	}
+	for i := range durs {
+		if id := rc.read()["id"]; id != float64(i) {
+			t.Fatalf("response %d has id %v", i, id)
+		}
+	}
+}
+
+func TestCancelRequestCancelsHandlerContext(t *testing.T) {
+	for _, opts := range [][]ServerOption{nil, {WithOrderedResponses()}} {
+		s := NewServer(opts...)
+		cancelled := make(chan error, 1)
+		s.Handle("slow", func(ctx *Context) {
+			select {
+			case <-ctx.Done():
+				cancelled <- ctx.Err()
+			case <-time.After(2 * time.Second):
+				cancelled <- nil
+			}
+			ctx.Result(1)
+		})
+		c := startServer(t, s)
+		call := c.Go("slow", nil, nil, make(chan *Call, 1))
+		time.Sleep(50 * time.Millisecond)
+		c.Cancel(call.ID)
+		if err := <-cancelled; err != context.Canceled {
+			t.Fatalf("handler ctx.Err() = %v, want context.Canceled", err)
+		}
+		if !c.Ping() {
+			t.Fatal("ping failed after cancel")
+		}
+	}
+}
+
+func TestHealth(t *testing.T) {
+	s := NewServer()
+	c := startServer(t, s)
+	c2, err := Dial(s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var h HealthStatus
+	if err := c.Call("rpc.health", nil, &h, 5); err != nil || h.Connections != 2 || h.Status != "ok" || h.Methods != 3 {
+		t.Fatalf("rpc.health = %v, %+v", err, h)
+	}
+	c2.Close()
+	deadline := time.Now().Add(time.Second)
+	for s.ConnectionCount() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := c.Call("rpc.health", nil, &h, 5); err != nil || h.Connections != 1 {
+		t.Fatalf("rpc.health after close = %v, %+v", err, h)
+	}
+
+	c3 := startServer(t, NewServer(WithoutHealth()))
+	if c3.Call("rpc.health", nil, nil, 5) == nil {
+		t.Fatal("rpc.health should not be registered with WithoutHealth")
+	}
+}
+
+func TestServeListener(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(5) })
+	served := make(chan error, 1)
+	go func() { served <- s.Serve(l) }()
+
+	c, err := Dial(l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var r int
+	if err := c.Call("x", nil, &r, 5); err != nil || r != 5 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+	if s.Listen("127.0.0.1:0") == nil {
+		t.Fatal("Listen succeeded while Serve was running")
+	}
+	s.Close(context.Background())
+	if err := <-served; err != nil {
+		t.Fatalf("Serve returned %v after Close", err)
+	}
+}
+
+func TestMissingMethodIsInvalidRequest(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+	for _, m := range []string{"", "   "} {
+		e, _ := AsRPCError(c.Call(m, nil, nil, 5))
+		if e == nil || e.Code != protocol.CodeInvalidRequest {
+			t.Fatalf("method %q: got %+v, want invalid request", m, e)
+		}
+	}
+	if err := c.Call("x", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNotificationFallback(t *testing.T) {
+	got := make(chan string, 2)
+	s := NewServer(WithNotificationFallback(func(ctx *Context) { got <- "fallback:" + ctx.Request.Method }))
+	s.Handle("known", func(ctx *Context) { got <- "known" })
+	c := startServer(t, s)
+	c.Notify("known", nil)
+	if v := <-got; v != "known" {
+		t.Fatalf("got %q, want the registered handler", v)
+	}
+	c.Notify("unknown", nil)
+	if v := <-got; v != "fallback:unknown" {
+		t.Fatalf("got %q, want the fallback", v)
+	}
+	// 请求不受通知回退的影响
+	if e, _ := AsRPCError(c.Call("unknown", nil, nil, 5)); e == nil || e.Code != protocol.CodeMethodNotFound {
+		t.Fatalf("got %+v, want method not found", e)
+	}
+}
+
+func TestUseNumber(t *testing.T) {
+	big := json.RawMessage(`{"ID":9007199254740993}`)
+	s := NewServer(WithUseNumber())
+	s.Handle("id", func(ctx *Context) {
+		var p struct{ ID interface{} }
+		if err := ctx.Bind(&p); err != nil {
+			ctx.Error(protocol.InternalError(err.Error()))
+			return
+		}
+		m, _ := ctx.Params()
+		n := p.ID.(json.Number)
+		if m["ID"].(json.Number) != n {
+			ctx.Error(protocol.InternalError("Bind and Params disagree"))
+			return
+		}
+		v, _ := n.Int64()
+		ctx.Result(v)
+	})
+	ctx := s.TestContext("id", big)
+	if r := ctx.GetResponseResult(); r != int64(9007199254740993) {
+		t.Fatalf("got %v, %v, want the exact integer", r, ctx.GetResponseError())
+	}
+
+	// 默认解码为 float64，超出精度的整数会被舍入
+	s2 := NewServer()
+	s2.Handle("id", func(ctx *Context) {
+		m, _ := ctx.Params()
+		ctx.Result(m["ID"])
+	})
+	if r := s2.TestContext("id", big).GetResponseResult(); r != float64(9007199254740992) {
+		t.Fatalf("got %v, want the rounded float64", r)
+	}
+}
+
+func TestOnError(t *testing.T) {
+	s := NewServer()
+	var mu sync.Mutex
+	var got []*protocol.ErrorObject
+	s.OnError(func(ctx *Context, err *protocol.ErrorObject) {
+		mu.Lock()
+		got = append(got, err)
+		mu.Unlock()
+	})
+	want := protocol.InvalidParamsError("nope")
+	s.Handle("bad", func(ctx *Context) { ctx.Error(want) })
+	s.Handle("ok", func(ctx *Context) { ctx.Result(1) })
+	s.Handle("guarded", func(ctx *Context) {
+		ctx.Error(protocol.InternalError("denied"))
+		ctx.Abort()
+	}, func(ctx *Context) { ctx.Result(1) })
+	c := startServer(t, s)
+
+	c.Call("ok", nil, nil, 5)
+	c.Call("bad", nil, nil, 5)
+	c.Call("guarded", nil, nil, 5)
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 2 || got[0] != want || got[1].Data != "denied" {
+		t.Fatalf("hook saw %+v, want the bad and guarded errors", got)
+	}
+}
+
+func TestAuthorizer(t *testing.T) {
+	s := NewServer()
+	var ran int32
+	s.Handle("admin.drop", func(ctx *Context) { atomic.AddInt32(&ran, 1); ctx.Result(1) })
+	s.Handle("user.get", func(ctx *Context) { ctx.Result(2) })
+	s.Handle("custom", func(ctx *Context) { atomic.AddInt32(&ran, 1); ctx.Result(3) })
+	s.SetAuthorizer(func(identity interface{}, method string, params json.RawMessage) error {
+		if strings.HasPrefix(method, "admin.") {
+			return errors.New("admin only")
+		}
+		if method == "custom" {
+			return protocol.NewError(-32010, "quota", nil)
+		}
+		return nil
+	})
+	c := startServer(t, s)
+
+	var r int
+	if err := c.Call("user.get", nil, &r, 5); err != nil || r != 2 {
+		t.Fatalf("user.get = %v, reply %d", err, r)
+	}
+	err := c.Call("admin.drop", nil, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeUnauthorized || e.Data != "admin only" {
+		t.Fatalf("admin.drop: got %v, want unauthorized", err)
+	}
+	// 返回 *protocol.ErrorObject 时原样写回
+	err = c.Call("custom", nil, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != -32010 {
+		t.Fatalf("custom: got %v, want the authorizer's error", err)
+	}
+	if n := atomic.LoadInt32(&ran); n != 0 {
+		t.Fatalf("%d rejected handlers ran", n)
+	}
+}
+
+func TestPingBypassesMiddleware(t *testing.T) {
+	s := NewServer()
+	var mwCalls int32
+	s.Use(func(ctx *Context) {
+		atomic.AddInt32(&mwCalls, 1)
+		ctx.Error(protocol.UnauthorizedError(nil))
+		ctx.Abort()
+	})
+	s.SetAuthorizer(func(interface{}, string, json.RawMessage) error { return errors.New("no") })
+	c := startServer(t, s)
+	if !c.Ping() {
+		t.Fatal("ping failed")
+	}
+	s.Use(func(ctx *Context) { ctx.Next() })
+	if !c.Ping() {
+		t.Fatal("ping failed after Use")
+	}
+	if n := atomic.LoadInt32(&mwCalls); n != 0 {
+		t.Fatalf("middleware ran %d times for ping", n)
+	}
+	// 自行注册的 ping 与普通方法一样经过中间件
+	s.Handle("ping", func(ctx *Context) { ctx.Result("pong") })
+	if c.Ping() {
+		t.Fatal("custom ping should go through the middleware")
+	}
+}
+
+func TestCloseFinishesInFlightThenClosesConnections(t *testing.T) {
+	s := NewServer(WithReadTimeout(time.Minute))
+	started := make(chan struct{})
+	s.Handle("slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.Result("done")
+	})
+	c := startServer(t, s)
+
+	var r string
+	call := c.Go("slow", nil, &r, nil)
+	<-started
+	closed := make(chan error)
+	go func() { closed <- s.Close(context.Background()) }()
+	<-call.Done
+	if call.Error != nil || r != "done" {
+		t.Fatalf("in-flight call = %v, reply %q", call.Error, r)
+	}
+	if err := <-closed; err != nil {
+		t.Fatal(err)
+	}
+	// 空闲的连接不必等到读超时就被关闭
+	deadline := time.Now().Add(time.Second)
+	for !c.isClosed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := c.Call("slow", nil, &r, 1); err == nil {
+		t.Fatal("expected the connection to be closed")
+	}
+}
+
+func TestSetMethodConcurrency(t *testing.T) {
+	s := NewServer()
+	var cur, peak int32
+	entered := make(chan struct{}, 10)
+	release := make(chan struct{})
+	s.Handle("report", func(ctx *Context) {
+		n := atomic.AddInt32(&cur, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		entered <- struct{}{}
+		<-release
+		atomic.AddInt32(&cur, -1)
+		ctx.Result(1)
+	})
+	s.Handle("cheap", func(ctx *Context) { ctx.Result(1) })
+	s.Alias("old", "report")
+	s.SetMethodConcurrency("report", 2)
+	c := startServer(t, s)
+
+	// 通过别名的调用与原方法共用同一个限制
+	done := make(chan *Call, 10)
+	for i := 0; i < 10; i++ {
+		m := "report"
+		if i%2 == 1 {
+			m = "old"
+		}
+		c.Go(m, nil, nil, done)
+	}
+	// 允许执行的两个调用阻塞在处理器中，其余的调用都应被立即拒绝
+	<-entered
+	<-entered
+	for i := 0; i < 8; i++ {
+		call := <-done
+		if e, ok := AsRPCError(call.Error); !ok || e.Code != -32000 {
+			t.Fatalf("call %d: got %v, want a server busy error", i, call.Error)
+		}
+	}
+	// 其他方法不受限制
+	if err := c.Call("cheap", nil, nil, 5); err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	for i := 0; i < 2; i++ {
+		if call := <-done; call.Error != nil {
+			t.Fatalf("permitted call failed: %v", call.Error)
+		}
+	}
+	if p := atomic.LoadInt32(&peak); p != 2 {
+		t.Fatalf("peak concurrency %d, want 2", p)
+	}
+}
+
+func TestCustomPingRegisteredBeforeListen(t *testing.T) {
+	s := NewServer()
+	var mwCalls int32
+	s.Use(func(ctx *Context) { atomic.AddInt32(&mwCalls, 1); ctx.Next() })
+	s.Handle("ping", func(ctx *Context) { ctx.Result("custom") })
+	c := startServer(t, s)
+	var r string
+	if err := c.Call("ping", nil, &r, 5); err != nil || r != "custom" {
+		t.Fatalf("ping = %v, %q, want the custom handler", err, r)
+	}
+	if n := atomic.LoadInt32(&mwCalls); n != 1 {
+		t.Fatalf("middleware ran %d times for the custom ping, want 1", n)
+	}
+}
@@ -0,0 +1,179 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// StreamMetaKey 是服务端流式调用的中间响应在 meta 中携带的标记，值为 "partial"。
+// 与请求 id 相同、不带该标记的响应是流的最终响应。与 $/cancelRequest 一样使用保留的 $/ 前缀，
+// 处理器不应通过 SetResponseMeta 设置这个 key。
+const StreamMetaKey = "$/stream"
+
+// streamPartial 是中间响应的 StreamMetaKey 取值
+const streamPartial = "partial"
+
+// Send 向客户端发送一个中间结果：写出一条与请求 id 相同、meta 中 $/stream 为 "partial" 的响应，
+// 客户端通过 CallServerStream 依次读取；中间响应与最终响应一样经过 SetResponseInterceptor 设置的拦截器。
+// 处理器返回后照常写回的最终响应结束这个流：ctx.Result 设置的非 null 结果作为流的最后一项，
+// 也可以调用 ctx.End 只结束流而不附带结果，ctx.Error 设置的错误作为整个调用的错误。中间响应与最终响应共用连接的写锁，因此按发送顺序到达。
+// 通知请求、Copy 得到的副本以及调用 End 之后不能发送，会返回错误。
+func (c *Context) Send(partial interface{}) error {
+	if c.detached {
+		return errors.New("jsonrpc2: send requires the original context")
+	}
+	if len(c.Request.ID) == 0 {
+		return errors.New("jsonrpc2: send requires a request id")
+	}
+	if c.sconn == nil || c.server == nil {
+		return errors.New("jsonrpc2: send requires a connection")
+	}
+	if c.streamEnded {
+		return errors.New("jsonrpc2: send after end of stream")
+	}
+	return c.server.writeResponseMeta(c, c.sconn, c.Request.ID, partial, map[string]string{StreamMetaKey: streamPartial})
+}
+
+// End 结束 Send 发送的流，最终响应的 result 为 null，客户端不会把它作为流的一项。之后 Send 返回错误。
+func (c *Context) End() {
+	if c.detached {
+		return
+	}
+	c.streamEnded = true
+	c.responseResult = nil
+}
+
+// ServerStream 是 CallServerStream 发起的服务端流式调用。
+type ServerStream struct {
+	// C 按到达顺序传递服务端通过 ctx.Send 发送的中间结果，以及最终响应中的非 null 结果；调用结束后关闭
+	C <-chan json.RawMessage
+
+	mu     sync.Mutex
+	cond   *sync.Cond
+	queue  []json.RawMessage
+	done   bool  // 调用已结束，不会再有新的结果
+	err    error // 调用的错误
+	closed chan struct{}
+}
+
+// Err 等待 C 关闭，然后返回调用的错误：服务端返回的错误、连接错误，或 ctx 被取消时的 ctx.Err()（超时为 ErrTimeout）。
+// 正常结束时返回 nil。
+func (s *ServerStream) Err() error {
+	<-s.closed
+	return s.err
+}
+
+// CallServerStream 发起一个服务端流式调用，服务端的处理器通过 ctx.Send 逐个发送结果。
+// 结果在接收循环中放入内存队列，读取过慢不会阻塞同一连接上的其他调用；
+// 调用方应当读完 C，或者取消 ctx 来提前结束调用（之后 C 中未读的结果被丢弃）。
+func (c *Client) CallServerStream(ctx context.Context, method string, args interface{}) (*ServerStream, error) {
+	id := c.nextID()
+	idKey, err := idToKey(id)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan json.RawMessage)
+	s := &ServerStream{C: out, closed: make(chan struct{})}
+	s.cond = sync.NewCond(&s.mu)
+	c.mutex.Lock()
+	if c.serverStreams == nil {
+		c.serverStreams = make(map[string]*ServerStream)
+	}
+	c.serverStreams[idKey] = s
+	c.mutex.Unlock()
+
+	var final json.RawMessage
+	call := &Call{
+		Method: method,
+		Args:   args,
+		Reply:  &final,
+		Meta:   MetaFromContext(ctx),
+		Done:   make(chan *Call, 1),
+	}
+	c.sendContext(ctx, id, call)
+
+	go func() {
+		err := c.awaitCall(ctx, id, call)
+		c.mutex.Lock()
+		delete(c.serverStreams, idKey)
+		c.mutex.Unlock()
+		if err == nil && len(final) > 0 && string(final) != "null" {
+			s.push(final)
+		}
+		s.finish(err)
+	}()
+
+	go func() {
+		defer close(s.closed)
+		defer close(out)
+		for {
+			item, ok := s.next()
+			if !ok {
+				return
+			}
+			select {
+			case out <- item:
+			case <-ctx.Done():
+				// 不再有人读取，等调用结束后记录错误再关闭
+				s.mu.Lock()
+				for !s.done {
+					s.cond.Wait()
+				}
+				s.mu.Unlock()
+				return
+			}
+		}
+	}()
+	return s, nil
+}
+
+// handleStreamPartial 将中间响应交给对应的流，没有对应的流时丢弃
+func (c *Client) handleStreamPartial(idKey string, result json.RawMessage) {
+	c.mutex.Lock()
+	s := c.serverStreams[idKey]
+	c.mutex.Unlock()
+	if s != nil {
+		s.push(result)
+	}
+}
+
+func (s *ServerStream) push(item json.RawMessage) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done {
+		return
+	}
+	s.queue = append(s.queue, item)
+	s.cond.Broadcast()
+}
+
+func (s *ServerStream) finish(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.done = true
+	s.err = err
+	s.cond.Broadcast()
+}
+
+// next 取出下一项结果，调用已结束且队列为空时返回 false
+func (s *ServerStream) next() (json.RawMessage, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for len(s.queue) == 0 && !s.done {
+		s.cond.Wait()
+	}
+	if len(s.queue) == 0 {
+		return nil, false
+	}
+	item := s.queue[0]
+	s.queue[0] = nil
+	s.queue = s.queue[1:]
+	return item, true
+}
+
+// isStreamPartial 报告响应是否为服务端流式调用的中间响应
+func isStreamPartial(meta map[string]string) bool {
+	return meta[StreamMetaKey] == streamPartial
+}
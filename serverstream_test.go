@@ -0,0 +1,121 @@
+package jsonrpc2
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestServerStream(t *testing.T) {
+	s := NewServer()
+	s.Handle("count", func(ctx *Context) {
+		var n int
+		ctx.Bind(&n)
+		for i := 1; i <= n; i++ {
+			if err := ctx.Send(i); err != nil {
+				return
+			}
+		}
+		ctx.Result("done")
+	})
+	s.Handle("ended", func(ctx *Context) {
+		ctx.Send(1)
+		ctx.End()
+		if ctx.Send(2) == nil {
+			t.Error("send after end")
+		}
+	})
+	s.Handle("failing", func(ctx *Context) {
+		ctx.Send(1)
+		ctx.Error(protocol.InternalError("boom"))
+	})
+	c := startServer(t, s)
+	st, err := c.CallServerStream(context.Background(), "count", 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for item := range st.C {
+		got = append(got, string(item))
+	}
+	if st.Err() != nil || strings.Join(got, ",") != `1,2,3,4,5,"done"` {
+		t.Fatal(st.Err(), got)
+	}
+	st, _ = c.CallServerStream(context.Background(), "ended", nil)
+	got = nil
+	for item := range st.C {
+		got = append(got, string(item))
+	}
+	if st.Err() != nil || strings.Join(got, ",") != `1` {
+		t.Fatal(st.Err(), got)
+	}
+	st, _ = c.CallServerStream(context.Background(), "failing", nil)
+	got = nil
+	for item := range st.C {
+		got = append(got, string(item))
+	}
+	if _, ok := AsRPCError(st.Err()); !ok || len(got) != 1 {
+		t.Fatal(st.Err(), got)
+	}
+	// 提前取消，之后未读的结果被丢弃
+	ctx, cancel := context.WithCancel(context.Background())
+	st, _ = c.CallServerStream(ctx, "count", 1000)
+	<-st.C
+	cancel()
+	if !errors.Is(st.Err(), context.Canceled) && st.Err() != nil {
+		t.Fatal(st.Err())
+	}
+	// 普通调用忽略中间响应，只取最终结果
+	var r string
+	if err := c.Call("count", 2, &r, 5); err != nil || r != "done" {
+		t.Fatalf("Call = %v, reply %q", err, r)
+	}
+}
+
+func TestServerStreamPartialsPassInterceptor(t *testing.T) {
+	s := NewServer()
+	s.Handle("count", func(ctx *Context) {
+		ctx.Send(1)
+		ctx.Send(2)
+		ctx.End()
+	})
+	var partials int32
+	s.SetResponseInterceptor(func(ctx *Context, resp *protocol.Response) {
+		if isStreamPartial(resp.Meta) {
+			atomic.AddInt32(&partials, 1)
+			resp.Meta["seen"] = "1"
+		}
+	})
+	c := startServer(t, s)
+	st, err := c.CallServerStream(context.Background(), "count", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := 0
+	for range st.C {
+		n++
+	}
+	if err := st.Err(); err != nil || n != 2 {
+		t.Fatalf("got %d items, err %v", n, err)
+	}
+	if p := atomic.LoadInt32(&partials); p != 2 {
+		t.Fatalf("interceptor saw %d partial responses, want 2", p)
+	}
+}
+
+func TestResponseMetaStreamLikeKeyDoesNotHang(t *testing.T) {
+	s := NewServer()
+	s.Handle("x", func(ctx *Context) {
+		ctx.SetResponseMeta("stream", "partial")
+		ctx.Result(7)
+	})
+	c := startServer(t, s)
+	var r int
+	if err := c.Call("x", nil, &r, 1); err != nil || r != 7 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+}
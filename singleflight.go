@@ -0,0 +1,89 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+)
+
+// WithSingleFlight 为指定的幂等方法启用调用合并：同一时刻（方法名, 编码后的参数）相同的多个调用
+// 只发出一个请求，所有调用方得到同一个结果或错误，适用于缓存击穿等大量并发读取相同数据的场景。
+// 共享的请求受第一个调用方的 ctx 控制，它被取消时其余调用方也会收到该错误；
+// 其余调用方各自的 ctx 被取消时只是不再等待。响应元数据（ContextWithCallMeta）只填入第一个调用方。
+// 与 WithResultCache 一样以拦截器的形式工作，位于手动添加的拦截器之前。
+func WithSingleFlight(methods ...string) DialOption {
+	return dialOptionFunc(func(c *Client) {
+		group := newFlightGroup(methods)
+		c.interceptors = append([]Interceptor{group.intercept}, c.interceptors...)
+	})
+}
+
+// flightGroup 记录正在进行中的可合并调用
+type flightGroup struct {
+	methods map[string]bool
+
+	mu      sync.Mutex
+	flights map[string]*flight
+}
+
+// flight 是一次被多个调用方共享的请求，done 关闭后 result 与 err 不再改变
+type flight struct {
+	done   chan struct{}
+	result json.RawMessage
+	err    error
+}
+
+func newFlightGroup(methods []string) *flightGroup {
+	g := &flightGroup{
+		methods: make(map[string]bool, len(methods)),
+		flights: make(map[string]*flight),
+	}
+	for _, m := range methods {
+		g.methods[m] = true
+	}
+	return g
+}
+
+func (g *flightGroup) intercept(next Invoker) Invoker {
+	return func(ctx context.Context, method string, args, reply interface{}) error {
+		if !g.methods[method] {
+			return next(ctx, method, args, reply)
+		}
+		key, ok := callKey(method, args)
+		if !ok {
+			return next(ctx, method, args, reply)
+		}
+
+		g.mu.Lock()
+		if f, ok := g.flights[key]; ok {
+			g.mu.Unlock()
+			select {
+			case <-f.done:
+			case <-ctx.Done():
+				if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+					return ErrTimeout
+				}
+				return ctx.Err()
+			}
+			if f.err != nil {
+				return f.err
+			}
+			return decodeResult(f.result, reply)
+		}
+		f := &flight{done: make(chan struct{})}
+		g.flights[key] = f
+		g.mu.Unlock()
+
+		f.err = next(ctx, method, args, &f.result)
+		g.mu.Lock()
+		delete(g.flights, key)
+		g.mu.Unlock()
+		close(f.done)
+
+		if f.err != nil {
+			return f.err
+		}
+		return decodeResult(f.result, reply)
+	}
+}
@@ -0,0 +1,54 @@
+package jsonrpc2
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSingleFlight(t *testing.T) {
+	s := NewServer()
+	var hits int32
+	release := make(chan struct{})
+	s.Handle("get", func(ctx *Context) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		ctx.Result(42)
+	})
+	startServer(t, s)
+	c, err := Dial(s.listener.Addr().String(), WithSingleFlight("get"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	var wg sync.WaitGroup
+	results := make([]int, 10)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if err := c.Call("get", map[string]int{"k": 1}, &results[i], 5); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	for i, r := range results {
+		if r != 42 {
+			t.Fatalf("results[%d] = %d, want 42", i, r)
+		}
+	}
+	if n := atomic.LoadInt32(&hits); n != 1 {
+		t.Fatalf("handler ran %d times for identical concurrent calls, want 1", n)
+	}
+	// 前一次调用结束后相同的调用会重新发出
+	var r int
+	c.Call("get", map[string]int{"k": 1}, &r, 5)
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Fatalf("handler ran %d times, want 2", n)
+	}
+}
@@ -0,0 +1,102 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// streamResult 是通过 ctx.Stream 设置的结果，在写回响应时才被调用
+type streamResult func(w io.Writer) error
+
+// Stream 将响应结果设置为一个写入函数：写回响应时 fn 直接把结果的 JSON 写到连接上，
+// 外层的 jsonrpc 与 id 成员由服务器负责，适用于无需在内存中构造完整结果的大数据量场景。
+// fn 必须写出恰好一个合法的 JSON 值；什么都不写则结果为 null。
+// fn 返回错误时，若连接上还没有写出任何数据（结果在写缓冲中尚未刷出也算），服务器改为返回 InternalError；
+// 已有部分数据写到连接上则只能关闭连接。
+// 使用自定义 Codec 或 Transport 时结果会先写入内存再整体发送。
+func (c *Context) Stream(fn func(w io.Writer) error) {
+	c.Result(streamResult(fn))
+}
+
+// writeStream 写回以 ctx.Stream 设置的结果，返回响应未能完整写出时的错误
+func (s *Server) writeStream(sc *serverConn, id json.RawMessage, stream streamResult) error {
+	if s.codec != nil || sc.transport != nil {
+		var buf bytes.Buffer
+		if err := stream(&buf); err != nil {
+			return s.writeResponse(sc, id, protocol.InternalError(err.Error()))
+		}
+		result := buf.Bytes()
+		if len(result) == 0 {
+			result = []byte("null")
+		}
+		return s.writeResponse(sc, id, json.RawMessage(result))
+	}
+
+	started, err := sc.writeStream(id, stream)
+	if err == nil {
+		return nil
+	}
+	if !started {
+		return s.writeResponse(sc, id, protocol.InternalError(err.Error()))
+	}
+	// 半条响应已经写出，连接上的消息边界已被破坏
+	s.log().Errorf("jsonrpc2: result stream failed midway, closing connection: %v", err)
+	sc.conn.Close()
+	return err
+}
+
+// writeStream 持有写锁，将响应信封与 stream 写出的结果经写缓冲依次写到连接上。
+// started 表示是否已有数据真正写到了连接上。
+func (sc *serverConn) writeStream(id json.RawMessage, stream streamResult) (started bool, err error) {
+	sc.sendMutex.Lock()
+	defer sc.sendMutex.Unlock()
+	if sc.writeTimeout > 0 {
+		sc.conn.SetWriteDeadline(time.Now().Add(sc.writeTimeout))
+	}
+
+	cw := &countingWriter{w: sc.conn}
+	w := &envelopeWriter{w: bufio.NewWriter(cw)}
+	if err := stream(w); err != nil {
+		return cw.n > 0, err
+	}
+	if !w.started {
+		w.Write([]byte("null"))
+	}
+	w.w.WriteString(`,"id":`)
+	w.w.Write(id)
+	w.w.WriteString("}\n")
+	return true, w.w.Flush()
+}
+
+// countingWriter 记录写到底层连接的字节数
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// envelopeWriter 在第一次写入前先写出响应信封的前半部分
+type envelopeWriter struct {
+	w       *bufio.Writer
+	started bool
+}
+
+func (e *envelopeWriter) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		if _, err := e.w.WriteString(`{"jsonrpc":"2.0","result":`); err != nil {
+			return 0, err
+		}
+	}
+	return e.w.Write(p)
+}
@@ -0,0 +1,56 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestContextStream(t *testing.T) {
+	for _, codec := range []Codec{nil, GzipJSONCodec{}} {
+		s := NewServer(WithCodec(codec))
+		s.Handle("big", func(ctx *Context) {
+			ctx.Stream(func(w io.Writer) error {
+				io.WriteString(w, "[")
+				for i := 0; i < 100000; i++ {
+					if i > 0 {
+						io.WriteString(w, ",")
+					}
+					fmt.Fprint(w, i)
+				}
+				_, err := io.WriteString(w, "]")
+				return err
+			})
+		})
+		s.Handle("empty", func(ctx *Context) { ctx.Stream(func(w io.Writer) error { return nil }) })
+		s.Handle("fail", func(ctx *Context) {
+			ctx.Stream(func(w io.Writer) error { io.WriteString(w, "[1,"); return errors.New("boom") })
+		})
+		startServer(t, s)
+		c, err := Dial(s.listener.Addr().String(), WithCodec(codec))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer c.Close()
+
+		var r []int
+		if err := c.CallWithID(77, "big", nil, &r, 5); err != nil || len(r) != 100000 || r[99999] != 99999 {
+			t.Fatalf("big: %v, %d items", err, len(r))
+		}
+		var x interface{} = 1
+		if err := c.Call("empty", nil, &x, 5); err != nil || x != nil {
+			t.Fatalf("empty: %v, reply %v", err, x)
+		}
+		// 写出一部分后失败时整个响应改为内部错误，连接仍然可用
+		err = c.Call("fail", nil, nil, 5)
+		if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeInternalError {
+			t.Fatalf("fail: got %v, want an internal error", err)
+		}
+		if !c.Ping() {
+			t.Fatal("ping failed after a failed stream")
+		}
+	}
+}
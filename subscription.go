@@ -0,0 +1,84 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// ErrSubscriptionClosed 表示订阅已被取消或所在连接已关闭。
+var ErrSubscriptionClosed = errors.New("jsonrpc2: subscription closed")
+
+// Subscription 代表一个服务端到客户端的推送流。
+// 通知的 method 为发起订阅的请求方法名，params 为 {"subscription": ID, "result": ...}，
+// 客户端可通过 Client.OnNotify 接收。
+type Subscription struct {
+	ID string
+
+	method string
+	sc     *serverConn
+
+	mu     sync.Mutex
+	closed bool
+	done   chan struct{}
+}
+
+// subscriptionParams 是订阅通知的 params 结构
+type subscriptionParams struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// Subscribe 在当前连接上创建一个订阅。处理器通常将 sub.ID 作为结果返回，
+// 并在后台 goroutine 中调用 sub.Notify 推送数据，直到 sub.Done() 被关闭。
+// 连接关闭时其上的所有订阅都会被自动清理。
+func (c *Context) Subscribe() *Subscription {
+	sub := &Subscription{
+		ID:     uuid.New().String(),
+		method: c.Request.Method,
+		sc:     c.sconn,
+		done:   make(chan struct{}),
+	}
+	if sub.sc == nil || !sub.sc.addSubscription(sub) {
+		sub.close()
+	}
+	return sub
+}
+
+// Notify 向订阅方推送一条通知，订阅关闭后返回 ErrSubscriptionClosed。
+func (sub *Subscription) Notify(params interface{}) error {
+	sub.mu.Lock()
+	closed := sub.closed
+	sub.mu.Unlock()
+	if closed {
+		return ErrSubscriptionClosed
+	}
+	raw, err := marshalValue(sub.sc.marshal, subscriptionParams{Subscription: sub.ID, Result: params})
+	if err != nil {
+		return err
+	}
+	return sub.sc.notify(sub.method, raw)
+}
+
+// Done 返回一个在订阅取消或连接关闭时被关闭的通道。
+func (sub *Subscription) Done() <-chan struct{} {
+	return sub.done
+}
+
+// Unsubscribe 取消订阅，之后的 Notify 调用都会返回 ErrSubscriptionClosed。
+func (sub *Subscription) Unsubscribe() {
+	if sub.sc != nil {
+		sub.sc.removeSubscription(sub.ID)
+	}
+	sub.close()
+}
+
+func (sub *Subscription) close() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		sub.closed = true
+		close(sub.done)
+	}
+}
@@ -0,0 +1,48 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestSubscription(t *testing.T) {
+	s := NewServer()
+	subs := make(chan *Subscription, 1)
+	s.Handle("feed", func(ctx *Context) {
+		sub := ctx.Subscribe()
+		ctx.Result(sub.ID)
+		subs <- sub
+	})
+	c := startServer(t, s)
+	got := make(chan string, 10)
+	c.OnNotify("feed", func(p json.RawMessage) { got <- string(p) })
+	var id string
+	if err := c.Call("feed", nil, &id, 5); err != nil {
+		t.Fatal(err)
+	}
+	sub := <-subs
+	for i := 0; i < 3; i++ {
+		if err := sub.Notify(i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		want := fmt.Sprintf(`{"subscription":"%s","result":%d}`, id, i)
+		if v := <-got; v != want {
+			t.Fatalf("notification %d = %s, want %s", i, v, want)
+		}
+	}
+
+	// 连接关闭后订阅被清理
+	c.Close()
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not closed with its connection")
+	}
+	if err := sub.Notify(1); err != ErrSubscriptionClosed {
+		t.Fatalf("Notify after close = %v, want ErrSubscriptionClosed", err)
+	}
+}
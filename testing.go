@@ -0,0 +1,87 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// TestServer 是通过 net.Pipe 在进程内相连的服务器与客户端，
+// 可在测试中完整地走一遍编解码与中间件，而无需监听真实端口。
+type TestServer struct {
+	*Server
+	Client *Client
+}
+
+// NewTestServer 创建一个服务器并通过 net.Pipe 为其连接一个客户端。
+// 处理器可以在返回后再通过 ts.Handle 注册；测试结束时应调用 ts.Close。
+func NewTestServer(opts ...ServerOption) *TestServer {
+	s := NewServer(opts...)
+	serverSide, clientSide := net.Pipe()
+	go s.ServeConn(serverSide)
+	return &TestServer{
+		Server: s,
+		Client: NewClient(clientSide),
+	}
+}
+
+// Pipe 创建通过 net.Pipe 相连的客户端与正在运行的服务器，并返回关闭二者的清理函数，
+// 便于下游在测试中不经过真实端口验证嵌入本库的代码。它等价于 NewTestServer 后分别取出客户端与服务器：
+//
+//	client, server, cleanup := jsonrpc2.Pipe()
+//	defer cleanup()
+//	server.Handle("Arith.Add", addHandler)
+//	err := client.Call("Arith.Add", []int{1, 2}, &reply, 5)
+func Pipe(opts ...ServerOption) (*Client, *Server, func()) {
+	ts := NewTestServer(opts...)
+	return ts.Client, ts.Server, ts.Close
+}
+
+// Close 关闭客户端与服务器。
+func (ts *TestServer) Close() {
+	ts.Client.Close()
+	ts.Server.Close(context.Background())
+}
+
+// TestContext 不经过网络直接执行一个方法的完整处理链（包括全局中间件与路由中间件），
+// 返回执行完毕的 Context，可通过 GetResponseResult 与 GetResponseError 断言结果。
+// 方法未注册且没有默认处理链时返回的 Context 带有 MethodNotFoundError；params 可以是 json.RawMessage。
+func (s *Server) TestContext(method string, params interface{}) *Context {
+	ctx := &Context{
+		Context:    context.WithValue(context.Background(), requestIDContextKey{}, json.Number("1")),
+		handlerIdx: -1,
+		Request: &protocol.Request{
+			Jsonrpc: "2.0",
+			Method:  method,
+			ID:      json.RawMessage("1"),
+		},
+	}
+
+	raw, ok := params.(json.RawMessage)
+	if !ok {
+		var err error
+		if raw, err = json.Marshal(params); err != nil {
+			ctx.responseError = protocol.InvalidParamsError(err.Error())
+			return ctx
+		}
+	}
+	ctx.Request.Params = raw
+
+	entry, found := s.router.find(method)
+	if !found {
+		entry = s.router.defaultEntry()
+	}
+	if entry == nil {
+		ctx.responseError = protocol.MethodNotFoundError(method)
+		return ctx
+	}
+	s.mu.Lock()
+	ctx.validator = s.validator
+	s.mu.Unlock()
+	ctx.useNumber = s.useNumber
+	ctx.handlerChain = entry.combined
+	ctx.Next()
+	return ctx
+}
@@ -0,0 +1,54 @@
+package jsonrpc2
+
+import (
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestTestServer(t *testing.T) {
+	ts := NewTestServer()
+	defer ts.Close()
+	ts.Use(func(ctx *Context) { ctx.Set("mw", true); ctx.Next() })
+	ts.Handle("add", func(ctx *Context) {
+		var p []int
+		if err := ctx.Bind(&p); err != nil {
+			ctx.Error(err.(*protocol.ErrorObject))
+			return
+		}
+		ctx.Result(p[0] + p[1])
+	})
+
+	var r int
+	if err := ts.Client.Call("add", []int{1, 2}, &r, 5); err != nil || r != 3 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+	if !ts.Client.Ping() {
+		t.Fatal("ping failed")
+	}
+
+	ctx := ts.TestContext("add", []int{2, 5})
+	if got := ctx.GetResponseResult(); got != 7 {
+		t.Fatalf("result = %v, want 7", got)
+	}
+	if v, _ := ctx.Get("mw"); v != true {
+		t.Fatal("middleware did not run for TestContext")
+	}
+	if e := ts.TestContext("nope", nil).GetResponseError(); e == nil || e.Code != protocol.CodeMethodNotFound {
+		t.Fatalf("got %+v, want method not found", e)
+	}
+}
+
+func TestPipe(t *testing.T) {
+	c, s, cleanup := Pipe()
+	defer cleanup()
+	s.Handle("x", func(ctx *Context) { ctx.Result(7) })
+	var r int
+	if err := c.Call("x", nil, &r, 5); err != nil || r != 7 {
+		t.Fatalf("Call = %v, reply %d", err, r)
+	}
+	cleanup()
+	if err := c.Call("x", nil, &r, 5); err == nil {
+		t.Fatal("expected calls to fail after cleanup")
+	}
+}
@@ -0,0 +1,54 @@
+package jsonrpc2
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+)
+
+// WithTLSConfig 使 Listen 以 TLS 提供服务。需要双向 TLS 时将 cfg.ClientAuth 设为
+// tls.RequireAndVerifyClientCert 并在 cfg.ClientCAs 中提供签发客户端证书的 CA，
+// 未提供有效证书的客户端会在握手阶段被拒绝，处理器可通过 ctx.PeerCertificate 读取客户端证书。
+// Serve 与 ServeConn 使用调用方提供的监听器或连接，可自行用 tls.NewListener 或 tls.Server 包装。
+func WithTLSConfig(cfg *tls.Config) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.tlsConfig = cfg
+	})
+}
+
+// DialTLS 以 TLS 连接到指定的 RPC 服务器；双向 TLS 时在 cfg.Certificates 中提供客户端证书。
+func DialTLS(addr string, cfg *tls.Config, opts ...DialOption) (*Client, error) {
+	conn, err := tls.Dial("tcp", addr, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn, opts...), nil
+}
+
+// PeerCertificate 返回 TLS 连接上客户端提供并通过校验的叶子证书，
+// 连接不是 TLS 连接或客户端没有提供证书时返回 nil。
+func (c *Context) PeerCertificate() *x509.Certificate {
+	return peerCertificate(c.Conn)
+}
+
+func peerCertificate(conn net.Conn) *x509.Certificate {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	state := tlsConn.ConnectionState()
+	if len(state.VerifiedChains) > 0 && len(state.VerifiedChains[0]) > 0 {
+		return state.VerifiedChains[0][0]
+	}
+	return nil
+}
+
+// tlsHandshake 在读取请求之前完成 TLS 握手，使握手失败在连接建立时即被发现，
+// 并保证处理器运行时对端证书已经可用。非 TLS 连接直接返回 nil。
+func tlsHandshake(conn net.Conn) error {
+	tlsConn, ok := conn.(*tls.Conn)
+	if !ok {
+		return nil
+	}
+	return tlsConn.Handshake()
+}
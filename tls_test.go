@@ -0,0 +1,96 @@
+package jsonrpc2
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+)
+
+// newTestCert 生成一个 CommonName 为 cn 的证书，ca 为 nil 时自签名
+func newTestCert(t *testing.T, cn string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey, tls.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: cn},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  ca == nil,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	parent, signer := tmpl, key
+	if ca != nil {
+		parent, signer = ca, caKey
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert, key, tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+}
+
+func TestPeerCertificate(t *testing.T) {
+	ca, caKey, _ := newTestCert(t, "ca", nil, nil)
+	_, _, serverCert := newTestCert(t, "server", ca, caKey)
+	_, _, clientCert := newTestCert(t, "alice", ca, caKey)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	s := NewServer(WithTLSConfig(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    pool,
+	}))
+	s.Handle("whoami", func(ctx *Context) {
+		cert := ctx.PeerCertificate()
+		if cert == nil {
+			ctx.Result("")
+			return
+		}
+		ctx.Result(cert.Subject.CommonName)
+	})
+	startServer(t, s)
+	addr := s.listener.Addr().String()
+
+	c, err := DialTLS(addr, &tls.Config{RootCAs: pool, Certificates: []tls.Certificate{clientCert}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	var r string
+	if err := c.Call("whoami", nil, &r, 5); err != nil || r != "alice" {
+		t.Fatalf("Call = %v, subject %q", err, r)
+	}
+
+	// 没有客户端证书时握手失败；TLS 1.3 下错误可能在第一次调用时才出现
+	noCert, err := DialTLS(addr, &tls.Config{RootCAs: pool})
+	if err == nil {
+		defer noCert.Close()
+		if err := noCert.Call("whoami", nil, &r, 2); err == nil {
+			t.Fatal("expected a call without a client certificate to fail")
+		}
+	}
+
+	plain := NewServer()
+	plain.Handle("whoami", func(ctx *Context) { ctx.Result(ctx.PeerCertificate() == nil) })
+	if r := plain.TestContext("whoami", nil).GetResponseResult(); r != true {
+		t.Fatal("PeerCertificate returned a certificate without TLS")
+	}
+}
@@ -0,0 +1,19 @@
+package jsonrpc2
+
+import (
+	"context"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// Tracer 在处理链执行前被调用，用于创建链路追踪的 span。
+// 传入的 ctx 携带请求的 meta（可通过 MetaFromContext 读取上游传递的 trace id），
+// 返回的 context 会成为处理器中 Context.Context，返回的函数在处理链结束后以最终的错误（成功时为 nil）调用。
+type Tracer func(ctx context.Context, method string) (context.Context, func(err *protocol.ErrorObject))
+
+// WithTracer 为服务器设置 Tracer。
+func WithTracer(t Tracer) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		s.tracer = t
+	})
+}
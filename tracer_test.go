@@ -0,0 +1,36 @@
+package jsonrpc2
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+type spanKey struct{}
+
+func TestWithTracer(t *testing.T) {
+	var ended *protocol.ErrorObject
+	var started, traceID string
+	s := NewServer(WithTracer(func(ctx context.Context, method string) (context.Context, func(*protocol.ErrorObject)) {
+		started = method
+		traceID = MetaFromContext(ctx)["trace"]
+		return context.WithValue(ctx, spanKey{}, "span"), func(e *protocol.ErrorObject) { ended = e }
+	}))
+	spanSeen := make(chan bool, 1)
+	s.Handle("x", func(ctx *Context) {
+		spanSeen <- ctx.Value(spanKey{}) == "span"
+		ctx.Error(protocol.InternalError(nil))
+	})
+	c := startServer(t, s)
+	c.CallWithMeta(map[string]string{"trace": "t1"}, "x", nil, nil, 5)
+	if !<-spanSeen {
+		t.Fatal("handler context does not carry the span")
+	}
+	if started != "x" || traceID != "t1" {
+		t.Fatalf("span started for %q with trace %q", started, traceID)
+	}
+	if ended == nil || ended.Code != protocol.CodeInternalError {
+		t.Fatalf("span ended with %v", ended)
+	}
+}
@@ -0,0 +1,119 @@
+package jsonrpc2
+
+import (
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// errTransportNotify 表示 Transport 上无法推送服务端发起的通知
+var errTransportNotify = errors.New("jsonrpc2: transport does not support server notifications")
+
+// Transport 抽象了逐条收发消息的传输层，使服务器的分发逻辑可以运行在 TCP 之外，
+// 例如消息队列的主题、Redis 列表或进程内的 channel。
+// Recv 阻塞直到收到一条请求，传输层关闭后应返回错误（正常结束时返回 io.EOF）；
+// Send 可能被多个 goroutine 调用，服务器保证同一 Transport 上的 Send 不会并发执行；
+// Close 释放资源并使阻塞中的 Recv 返回。
+type Transport interface {
+	Recv() (*protocol.Request, error)
+	Send(resp *protocol.Response) error
+	Close() error
+}
+
+// ServeTransport 在 t 上提供服务，阻塞直至 Recv 返回错误或服务器关闭，返回时 t 已被关闭。
+// Transport 上不进行鉴权握手，也不支持订阅与 Broadcast 等服务端推送；ctx.Conn 为 nil。
+// 服务器关闭时停止接收新请求，等待正在处理的请求写回响应后关闭 t。
+func (s *Server) ServeTransport(t Transport) {
+	s.registerBuiltins()
+	sc := newTransportConn(t)
+	if !s.trackConn(sc) {
+		t.Close()
+		return
+	}
+	s.handleConnection(sc)
+}
+
+// readTransport 从 Transport 接收请求并分发，直至 Recv 出错或 stopReading 被调用。
+// Recv 在独立的 goroutine 中执行，使服务器关闭时无需等待阻塞中的 Recv 返回。
+func (s *Server) readTransport(sc *serverConn) {
+	reqs := make(chan *protocol.Request)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			req, err := sc.transport.Recv()
+			if err != nil {
+				errs <- err
+				return
+			}
+			select {
+			case reqs <- req:
+			case <-sc.stop:
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case req := <-reqs:
+			s.dispatch(sc, req, nil)
+		case err := <-errs:
+			if err != io.EOF {
+				s.log().Errorf("jsonrpc2: failed to receive from transport: %v", err)
+			}
+			return
+		case <-sc.stop:
+			return
+		}
+	}
+}
+
+// ChannelTransport 是基于 channel 的进程内 Transport：调用方向 Requests 写入请求，
+// 从 Responses 读取响应。响应的 Result 是处理器设置的 Go 值本身，不经过 JSON 编码。
+type ChannelTransport struct {
+	Requests  chan *protocol.Request
+	Responses chan *protocol.Response
+
+	done chan struct{}
+	once sync.Once
+}
+
+// NewChannelTransport 创建一个 ChannelTransport，buffer 为两个 channel 的缓冲大小。
+func NewChannelTransport(buffer int) *ChannelTransport {
+	return &ChannelTransport{
+		Requests:  make(chan *protocol.Request, buffer),
+		Responses: make(chan *protocol.Response, buffer),
+		done:      make(chan struct{}),
+	}
+}
+
+// Recv 实现了 Transport 接口。Requests 被关闭时返回 io.EOF。
+func (t *ChannelTransport) Recv() (*protocol.Request, error) {
+	select {
+	case req, ok := <-t.Requests:
+		if !ok {
+			return nil, io.EOF
+		}
+		return req, nil
+	case <-t.done:
+		return nil, io.ErrClosedPipe
+	}
+}
+
+// Send 实现了 Transport 接口。
+func (t *ChannelTransport) Send(resp *protocol.Response) error {
+	select {
+	case t.Responses <- resp:
+		return nil
+	case <-t.done:
+		return io.ErrClosedPipe
+	}
+}
+
+// Close 实现了 Transport 接口，可重复调用。
+func (t *ChannelTransport) Close() error {
+	t.once.Do(func() { close(t.done) })
+	return nil
+}
@@ -0,0 +1,53 @@
+package jsonrpc2
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestServeTransport(t *testing.T) {
+	s := NewServer()
+	s.Handle("add", func(ctx *Context) {
+		var p []int
+		ctx.Bind(&p)
+		ctx.Result(p[0] + p[1])
+	})
+	tr := NewChannelTransport(4)
+	done := make(chan struct{})
+	go func() { s.ServeTransport(tr); close(done) }()
+
+	tr.Requests <- &protocol.Request{Jsonrpc: "2.0", Method: "add", Params: json.RawMessage(`[1,2]`), ID: json.RawMessage(`7`)}
+	resp := <-tr.Responses
+	if resp.Result != 3 || string(resp.ID.(json.RawMessage)) != "7" {
+		t.Fatalf("got %+v, want result 3 for id 7", resp)
+	}
+	tr.Requests <- &protocol.Request{Jsonrpc: "2.0", Method: "nope", ID: json.RawMessage(`8`)}
+	if resp := <-tr.Responses; resp.Error == nil || resp.Error.Code != protocol.CodeMethodNotFound {
+		t.Fatalf("got %+v, want method not found", resp)
+	}
+	tr.Requests <- &protocol.Request{Jsonrpc: "2.0", Method: "ping", ID: json.RawMessage(`9`)}
+	if resp := <-tr.Responses; resp.Result != "pong" {
+		t.Fatalf("got %+v, want pong", resp)
+	}
+
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
+
+func TestServeTransportReturnsWhenRequestsClosed(t *testing.T) {
+	tr := NewChannelTransport(1)
+	close(tr.Requests)
+	done := make(chan struct{})
+	go func() { NewServer().ServeTransport(tr); close(done) }()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("ServeTransport did not return after Requests was closed")
+	}
+}
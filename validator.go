@@ -0,0 +1,76 @@
+package jsonrpc2
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Validator 在参数绑定完成后对结果进行校验。
+// 可通过 Server.SetValidator 安装自定义实现（例如对 go-playground/validator 的封装）。
+type Validator interface {
+	Validate(v interface{}) error
+}
+
+// ValidatorFunc 是一个函数适配器，使普通函数满足 Validator 接口。
+type ValidatorFunc func(v interface{}) error
+
+// Validate 实现了 Validator 接口。
+func (f ValidatorFunc) Validate(v interface{}) error {
+	return f(v)
+}
+
+// requiredValidator 是默认的校验器，只支持 `validate:"required"` 标签：
+// 带有该标签的字段不能是零值。
+type requiredValidator struct{}
+
+func (requiredValidator) Validate(v interface{}) error {
+	return validateRequired(reflect.ValueOf(v), "")
+}
+
+func validateRequired(rv reflect.Value, prefix string) error {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		field := rt.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := prefix + fieldName(field)
+		fv := rv.Field(i)
+		if hasRule(field.Tag.Get("validate"), "required") && fv.IsZero() {
+			return fmt.Errorf("field '%s' is required", name)
+		}
+		if err := validateRequired(fv, name+"."); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldName 返回字段在 JSON 中的名称，优先使用 json 标签。
+func fieldName(field reflect.StructField) string {
+	if tag := field.Tag.Get("json"); tag != "" {
+		if name := strings.Split(tag, ",")[0]; name != "" && name != "-" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+func hasRule(tag, rule string) bool {
+	for _, r := range strings.Split(tag, ",") {
+		if strings.TrimSpace(r) == rule {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,32 @@
+package jsonrpc2
+
+import (
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestBindAndValidate(t *testing.T) {
+	s := NewServer()
+	s.Handle("v", func(ctx *Context) {
+		var p struct {
+			Name string `json:"name" validate:"required"`
+		}
+		if err := ctx.BindAndValidate(&p); err != nil {
+			ctx.Error(err.(*protocol.ErrorObject))
+			return
+		}
+		ctx.Result(p.Name)
+	})
+	c := startServer(t, s)
+
+	err := c.Call("v", map[string]int{}, nil, 5)
+	e, ok := AsRPCError(err)
+	if !ok || e.Code != protocol.CodeInvalidParams || e.Data != "field 'name' is required" {
+		t.Fatalf("got %v", err)
+	}
+	var r string
+	if err := c.Call("v", map[string]string{"name": "bob"}, &r, 5); err != nil || r != "bob" {
+		t.Fatalf("Call = %v, reply %q", err, r)
+	}
+}
@@ -0,0 +1,93 @@
+package jsonrpc2
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// VersionMetaKey 是请求 meta 中携带 API 版本的 key，值如 "v2"。
+const VersionMetaKey = "version"
+
+// VersionRouter 配置按 API 版本分发请求，参见 WithVersionRouter。
+type VersionRouter struct {
+	// Versions 是支持的版本，如 "v1"、"v2"，版本号的形式为 v 加数字
+	Versions []string
+	// Default 是请求没有指定版本时使用的版本，为空表示按原方法名分发
+	Default string
+}
+
+// WithVersionRouter 使服务端按版本分发请求，各版本的方法以版本号为前缀注册，例如 v1.user.get 与 v2.user.get
+// （可以用 Group("v1") 注册）。请求的版本按以下顺序确定：方法名的版本前缀（如 v2.user.get）、
+// meta 中的 version 成员、VersionRouter.Default；没有前缀的请求会被分发到对应版本的方法。
+// 版本不在 Versions 中的请求得到 UnsupportedVersionError（-32004），data 中列出支持的版本。
+// 未带版本前缀的方法名本身已注册时（如内置的 ping、rpc.discover）直接分发，不受版本影响。
+// 版本只影响分发：处理器、中间件、OnError 钩子与 tracer 看到的 ctx.Request.Method 仍是请求中的原方法名，
+// 而鉴权函数与 SetMethodConcurrency 的限制按实际分发到的带版本前缀的方法名生效。
+// 设置了 WithCaseInsensitiveMethods 时版本号同样不区分大小写（如 V2.user.get）。
+func WithVersionRouter(vr VersionRouter) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		supported := make(map[string]bool, len(vr.Versions))
+		for _, v := range vr.Versions {
+			supported[v] = true
+		}
+		s.versions = &versionRouter{
+			versions:  append([]string(nil), vr.Versions...),
+			supported: supported,
+			fallback:  vr.Default,
+		}
+	})
+}
+
+// versionRouter 是 WithVersionRouter 的运行时状态
+type versionRouter struct {
+	versions  []string
+	supported map[string]bool
+	fallback  string
+}
+
+// resolveVersion 确定请求的版本并返回用于分发的方法名，版本不受支持时返回错误。
+// req.Method 保持不变；设置了 WithCaseInsensitiveMethods 时版本号同样不区分大小写。
+func (s *Server) resolveVersion(req *protocol.Request) (string, *protocol.ErrorObject) {
+	vr := s.versions
+	if prefix, _, ok := strings.Cut(req.Method, "."); ok && isVersion(s.router.normalize(prefix)) {
+		return req.Method, vr.check(s.router.normalize(prefix))
+	}
+	if _, found := s.router.find(req.Method); found {
+		return req.Method, nil
+	}
+	version := req.Meta[VersionMetaKey]
+	if version == "" {
+		version = vr.fallback
+	}
+	if version == "" {
+		return req.Method, nil
+	}
+	version = s.router.normalize(version)
+	if err := vr.check(version); err != nil {
+		return "", err
+	}
+	return version + "." + req.Method, nil
+}
+
+func (vr *versionRouter) check(version string) *protocol.ErrorObject {
+	if vr.supported[version] {
+		return nil
+	}
+	return protocol.UnsupportedVersionError(fmt.Sprintf("unsupported version %q, supported versions: %s",
+		version, strings.Join(vr.versions, ", ")))
+}
+
+// isVersion 报告 s 是否为 v 加数字形式的版本号
+func isVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	for _, c := range s[1:] {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
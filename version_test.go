@@ -0,0 +1,60 @@
+package jsonrpc2
+
+import (
+	"testing"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+func TestVersionRouter(t *testing.T) {
+	s := NewServer(WithVersionRouter(VersionRouter{Versions: []string{"v1", "v2"}, Default: "v1"}))
+	s.Handle("v1.foo", func(ctx *Context) { ctx.Result("one:" + ctx.Request.Method) })
+	s.Group("v2").Handle("foo", func(ctx *Context) { ctx.Result("two") })
+	c := startServer(t, s)
+	var r string
+	if err := c.Call("v1.foo", nil, &r, 5); err != nil || r != "one:v1.foo" {
+		t.Fatal(err, r)
+	}
+	if err := c.Call("v2.foo", nil, &r, 5); err != nil || r != "two" {
+		t.Fatal(err, r)
+	}
+	// 按版本分发不改写请求中的方法名
+	if err := c.Call("foo", nil, &r, 5); err != nil || r != "one:foo" {
+		t.Fatal(err, r)
+	}
+	if err := c.CallWithMeta(map[string]string{"version": "v2"}, "foo", nil, &r, 5); err != nil || r != "two" {
+		t.Fatal(err, r)
+	}
+	err := c.Call("v3.foo", nil, &r, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != protocol.CodeUnsupportedVersion {
+		t.Fatal(err)
+	}
+	if !c.Ping() {
+		t.Fatal("ping")
+	}
+	if _, err := c.Discover(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVersionRouterCaseInsensitive(t *testing.T) {
+	s := NewServer(WithCaseInsensitiveMethods(), WithVersionRouter(VersionRouter{Versions: []string{"v1", "v2"}}))
+	s.Handle("v2.foo", func(ctx *Context) { ctx.Result("two") })
+	var failed []string
+	s.OnError(func(ctx *Context, err *protocol.ErrorObject) { failed = append(failed, ctx.Request.Method) })
+	s.Handle("v2.bad", func(ctx *Context) { ctx.Error(protocol.InternalError(nil)) })
+	c := startServer(t, s)
+	var r string
+	if err := c.Call("V2.foo", nil, &r, 5); err != nil || r != "two" {
+		t.Fatalf("V2.foo: %v, reply %q", err, r)
+	}
+	if err := c.CallWithMeta(map[string]string{VersionMetaKey: "V2"}, "foo", nil, &r, 5); err != nil || r != "two" {
+		t.Fatalf("foo with version V2: %v, reply %q", err, r)
+	}
+	if err := c.CallWithMeta(map[string]string{VersionMetaKey: "v2"}, "bad", nil, nil, 5); err == nil {
+		t.Fatal("expected an error")
+	}
+	if len(failed) != 1 || failed[0] != "bad" {
+		t.Fatalf("OnError saw methods %v, want [bad]", failed)
+	}
+}
@@ -0,0 +1,116 @@
+package jsonrpc2
+
+import (
+	"sync"
+
+	"github.com/kyle-cao/jsonrpc2/protocol"
+)
+
+// WithWorkerPool 使用一个全局的工作池处理所有连接上的请求，而不是为每个请求启动独立的 goroutine。
+// 最多 workers 个请求同时处理；等待处理的请求按连接排队，工作协程在有排队请求的连接之间轮转取用，
+// 因此大量流水线请求的连接不会让请求较少的连接饿死。排队的请求数达到 queueSize 时，
+// 新到达的请求立即得到 -32000 Server busy 错误（通知被丢弃）；queueSize <= 0 表示不限制队列长度。
+// workers <= 0 表示不使用工作池（默认）。
+func WithWorkerPool(workers, queueSize int) ServerOption {
+	return serverOptionFunc(func(s *Server) {
+		if workers <= 0 {
+			s.pool = nil
+			return
+		}
+		s.pool = newWorkerPool(workers, queueSize)
+	})
+}
+
+// errServerBusy 是工作池队列已满时返回给客户端的错误
+func errServerBusy() *protocol.ErrorObject {
+	return protocol.NewError(protocol.CodeServerError, "Server busy", nil)
+}
+
+// workerPool 为每个连接维护一个先进先出的队列，并在连接之间轮流调度
+type workerPool struct {
+	workers int
+	size    int
+	start   sync.Once
+
+	mu      sync.Mutex
+	cond    *sync.Cond
+	queues  map[*serverConn][]func()
+	ring    []*serverConn // 有排队请求的连接，按轮转顺序排列
+	queued  int
+	stopped bool
+}
+
+func newWorkerPool(workers, size int) *workerPool {
+	p := &workerPool{
+		workers: workers,
+		size:    size,
+		queues:  make(map[*serverConn][]func()),
+	}
+	p.cond = sync.NewCond(&p.mu)
+	return p
+}
+
+// submit 将任务放入 sc 的队列，队列已满或工作池已停止时返回 false
+func (p *workerPool) submit(sc *serverConn, task func()) bool {
+	p.start.Do(func() {
+		for i := 0; i < p.workers; i++ {
+			go p.work()
+		}
+	})
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.stopped || (p.size > 0 && p.queued >= p.size) {
+		return false
+	}
+	if len(p.queues[sc]) == 0 {
+		p.ring = append(p.ring, sc)
+	}
+	p.queues[sc] = append(p.queues[sc], task)
+	p.queued++
+	p.cond.Signal()
+	return true
+}
+
+func (p *workerPool) work() {
+	for {
+		task, ok := p.next()
+		if !ok {
+			return
+		}
+		task()
+	}
+}
+
+// next 从轮转顺序中的下一个连接取出一个任务；工作池停止且队列为空时返回 false
+func (p *workerPool) next() (func(), bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.ring) == 0 && !p.stopped {
+		p.cond.Wait()
+	}
+	if len(p.ring) == 0 {
+		return nil, false
+	}
+	sc := p.ring[0]
+	p.ring = p.ring[1:]
+	queue := p.queues[sc]
+	task := queue[0]
+	queue[0] = nil
+	if queue = queue[1:]; len(queue) == 0 {
+		delete(p.queues, sc)
+	} else {
+		// 该连接还有排队的请求，排到轮转顺序的末尾
+		p.queues[sc] = queue
+		p.ring = append(p.ring, sc)
+	}
+	p.queued--
+	return task, true
+}
+
+// stop 拒绝新的任务，工作协程处理完已排队的任务后退出
+func (p *workerPool) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopped = true
+	p.cond.Broadcast()
+}
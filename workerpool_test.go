@@ -0,0 +1,78 @@
+package jsonrpc2
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolSchedulesConnectionsFairly(t *testing.T) {
+	s := NewServer(WithWorkerPool(1, 100))
+	var mu sync.Mutex
+	var order []string
+	gate := make(chan struct{})
+	s.Handle("work", func(ctx *Context) {
+		var who string
+		ctx.Bind(&who)
+		if who == "gate" {
+			<-gate
+		}
+		mu.Lock()
+		order = append(order, who)
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		ctx.Result(who)
+	})
+	noisy := startServer(t, s)
+	quiet, err := Dial(s.listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer quiet.Close()
+
+	// 唯一的 worker 被占用时，一个连接先排入大量请求，另一个连接随后只发一个
+	g := noisy.Go("work", "gate", new(string), nil)
+	time.Sleep(20 * time.Millisecond)
+	var calls []*Call
+	for i := 0; i < 30; i++ {
+		calls = append(calls, noisy.Go("work", "noisy", new(string), nil))
+	}
+	time.Sleep(50 * time.Millisecond)
+	q := quiet.Go("work", "quiet", new(string), nil)
+	time.Sleep(50 * time.Millisecond)
+	close(gate)
+	WaitAll(append(calls, g, q)...)
+
+	mu.Lock()
+	defer mu.Unlock()
+	idx := -1
+	for i, w := range order {
+		if w == "quiet" {
+			idx = i
+		}
+	}
+	// 按连接轮转时，quiet 的请求不必等 noisy 排在前面的 30 个请求全部执行完
+	if idx < 0 || idx > 3 {
+		t.Fatalf("quiet request ran at position %d: %v", idx, order)
+	}
+}
+
+func TestWorkerPoolRejectsWhenQueueIsFull(t *testing.T) {
+	s := NewServer(WithWorkerPool(1, 1))
+	block := make(chan struct{})
+	s.Handle("b", func(ctx *Context) { <-block; ctx.Result(1) })
+	c := startServer(t, s)
+	a1 := c.Go("b", nil, nil, nil)
+	time.Sleep(20 * time.Millisecond)
+	a2 := c.Go("b", nil, nil, nil)
+	time.Sleep(20 * time.Millisecond)
+	err := c.Call("b", nil, nil, 5)
+	if e, ok := AsRPCError(err); !ok || e.Code != -32000 || e.Message != "Server busy" {
+		t.Fatalf("got %v, want a server busy error", err)
+	}
+	close(block)
+	WaitAll(a1, a2)
+	if a1.Error != nil || a2.Error != nil {
+		t.Fatalf("queued calls failed: %v, %v", a1.Error, a2.Error)
+	}
+}
@@ -0,0 +1,113 @@
+package jsonrpc2
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// WithWriteBuffer 使客户端经过大小为 size 的写缓冲向连接写入消息，将高频调用产生的大量小消息合并为较少的 TCP 写入。
+// 每条消息写入缓冲后，如果还有其他 goroutine 正在等待写入，则由最后一个写入者负责刷新，从而合并突发的消息；
+// 否则 flushDelay 为 0 时立即刷新，大于 0 时最多延迟 flushDelay 后刷新，以增加少量延迟为代价进一步合并消息。
+// 因此只有一个调用在途时消息同样会被及时发出。所有写入仍在同一把写锁中进行，不会改变消息的顺序；
+// Close 与 Shutdown 在关闭连接前会先刷新缓冲。size <= 0 表示不使用写缓冲（默认）。
+func WithWriteBuffer(size int, flushDelay time.Duration) DialOption {
+	return dialOptionFunc(func(c *Client) {
+		c.writeBufferSize = size
+		c.flushDelay = flushDelay
+	})
+}
+
+// writeBuffered 将消息写入写缓冲，并按 WithWriteBuffer 的策略刷新
+func (c *Client) writeBuffered(v interface{}) error {
+	atomic.AddInt32(&c.writers, 1)
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	err := writeMessage(c.conn, c.encoder, c.writeTimeout, v)
+	// 写入完成后再检查等待者：此后才开始等待的写入者会自己刷新，不会有消息遗留在缓冲中
+	if atomic.AddInt32(&c.writers, -1) > 0 {
+		return err
+	}
+	// 本条消息编码失败时缓冲中仍可能有之前的写入者留下的完整消息，同样需要刷新
+	if flushErr := c.scheduleFlushLocked(); err == nil {
+		err = flushErr
+	}
+	return err
+}
+
+// scheduleFlushLocked 在缓冲中有数据时按 flushDelay 立即刷新或安排延迟刷新，调用方需持有写锁
+func (c *Client) scheduleFlushLocked() error {
+	if c.flushDelay <= 0 {
+		return c.flushLocked()
+	}
+	if !c.flushPending && c.writeBuf.Buffered() > 0 {
+		c.flushPending = true
+		if c.flushTimer == nil {
+			c.flushTimer = time.AfterFunc(c.flushDelay, c.delayedFlush)
+		} else {
+			c.flushTimer.Reset(c.flushDelay)
+		}
+	}
+	return nil
+}
+
+// delayedFlush 在 flushDelay 到期后刷新写缓冲，失败时连接已不可用，直接关闭
+func (c *Client) delayedFlush() {
+	c.sendMutex.Lock()
+	defer c.sendMutex.Unlock()
+	c.flushPending = false
+	if err := c.flushLocked(); err != nil {
+		c.logger.Errorf("jsonrpc2: failed to flush write buffer: %v", err)
+		c.conn.Close()
+	}
+}
+
+// flushLocked 在写入期限内将写缓冲中的数据写到连接上，调用方需持有写锁
+func (c *Client) flushLocked() error {
+	if c.writeBuf.Buffered() == 0 {
+		return nil
+	}
+	if c.writeTimeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(c.writeTimeout))
+	}
+	err := c.writeBuf.Flush()
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		c.conn.Close()
+	}
+	return err
+}
+
+// closeConn 尽量发出写缓冲中尚未发出的消息，然后关闭连接。正在写入的 goroutine 持有写锁时由它负责刷新，
+// 刷新最多等待 writeTimeout（未设置时为 closeFlushTimeout），不会因对端不再读取而阻塞关闭。
+func (c *Client) closeConn() error {
+	if c.writeBuf != nil && c.sendMutex.TryLock() {
+		if c.flushTimer != nil {
+			c.flushTimer.Stop()
+			c.flushPending = false
+		}
+		if c.writeBuf.Buffered() > 0 {
+			timeout := c.writeTimeout
+			if timeout <= 0 {
+				timeout = closeFlushTimeout
+			}
+			c.conn.SetWriteDeadline(time.Now().Add(timeout))
+			c.writeBuf.Flush()
+		}
+		c.sendMutex.Unlock()
+	}
+	return c.conn.Close()
+}
+
+// closeFlushTimeout 是关闭连接前刷新写缓冲的默认期限
+const closeFlushTimeout = time.Second
+
+// newWriteBuffer 按 WithWriteBuffer 的设置为 conn 创建写缓冲，未设置时返回 nil
+func (c *Client) newWriteBuffer() *bufio.Writer {
+	if c.writeBufferSize <= 0 {
+		return nil
+	}
+	return bufio.NewWriterSize(c.conn, c.writeBufferSize)
+}
@@ -0,0 +1,93 @@
+package jsonrpc2
+
+import (
+	"encoding/json"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// countConn 统计对连接的 Write 次数
+type countConn struct {
+	net.Conn
+	writes int64
+}
+
+func (c *countConn) Write(p []byte) (int, error) {
+	atomic.AddInt64(&c.writes, 1)
+	return c.Conn.Write(p)
+}
+
+func TestWriteBuffer(t *testing.T) {
+	s := NewServer()
+	s.Handle("echo", func(ctx *Context) { ctx.Result(ctx.Request.Params) })
+	l := startCountingServer(t, s)
+	for _, delay := range []time.Duration{0, 2 * time.Millisecond} {
+		raw, err := net.Dial("tcp", l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		cc := &countConn{Conn: raw}
+		c := NewClient(cc, WithWriteBuffer(4096, delay))
+		// 只有一个调用在途时消息同样会被及时发出
+		var r []int
+		if err := c.Call("echo", []int{1}, &r, 5); err != nil || r[0] != 1 {
+			t.Fatalf("delay %v: %v, reply %v", delay, err, r)
+		}
+		var calls []*Call
+		for i := 0; i < 500; i++ {
+			calls = append(calls, c.Go("echo", []int{i}, new([]int), nil))
+		}
+		WaitAll(calls...)
+		for i, call := range calls {
+			if call.Error != nil || (*call.Reply.(*[]int))[0] != i {
+				t.Fatalf("delay %v: call %d: %v", delay, i, call.Error)
+			}
+		}
+		t.Logf("delay %v: %d writes for 501 calls", delay, atomic.LoadInt64(&cc.writes))
+		c.Close()
+	}
+}
+
+func TestWriteBufferFlushesAfterEncodeError(t *testing.T) {
+	s := NewServer()
+	s.Handle("echo", func(ctx *Context) { ctx.Result(ctx.Request.Params) })
+	l := startCountingServer(t, s)
+	for _, delay := range []time.Duration{0, 2 * time.Millisecond} {
+		c, err := Dial(l.Addr().String(), WithWriteBuffer(4096, delay))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 模拟有另一个写入者在等待：本条消息写入缓冲后不刷新，交给下一个写入者
+		atomic.AddInt32(&c.writers, 1)
+		call := c.Go("echo", []int{1}, new([]int), make(chan *Call, 1))
+		atomic.AddInt32(&c.writers, -1)
+		// 下一个写入者的消息编码失败，仍要把缓冲中上一条完整的消息发出
+		if err := c.CallRaw("echo", json.RawMessage("{"), nil, 5); err == nil {
+			t.Fatal("expected an encode error")
+		}
+		select {
+		case r := <-call.Done:
+			if r.Error != nil {
+				t.Fatal(r.Error)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("delay %v: buffered message was not flushed after an encode error", delay)
+		}
+		c.Close()
+	}
+}
+
+func BenchmarkCallUnbuffered(b *testing.B) { benchmarkWriteBuffer(b) }
+
+func BenchmarkCallBuffered(b *testing.B) { benchmarkWriteBuffer(b, WithWriteBuffer(32<<10, 0)) }
+
+func benchmarkWriteBuffer(b *testing.B, opts ...DialOption) {
+	c, err := Dial(newBenchServer(b), opts...)
+	if err != nil {
+		b.Fatal(err)
+	}
+	defer c.Close()
+	benchmarkParallelCalls(b, func() error { return c.Call("echo", nil, nil, 5) })
+}